@@ -45,10 +45,89 @@ const (
 	MarkerXMLDocument Marker = 0x0F
 	// MarkerTypedObject A marker for TypedObject types
 	MarkerTypedObject Marker = 0x10
+	// MarkerAVMPlusObject A marker which switches to AMF3 for the next value
+	MarkerAVMPlusObject Marker = 0x11
 )
 
 // ECMAArray EcmaArray representation in Golang
 type ECMAArray map[string]interface{}
 
+// KeyValue A pair of a key and a value of OrderedObject and OrderedECMAArray
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedObject Object representation in Golang which keeps the order of keys
+type OrderedObject []KeyValue
+
+// Get Returns a value of the first pair which has the key
+func (o OrderedObject) Get(key string) (interface{}, bool) {
+	return getKeyValue(o, key)
+}
+
+// Set Replaces a value of the first pair which has the key, or appends the pair if not exists
+func (o *OrderedObject) Set(key string, value interface{}) {
+	*o = setKeyValue(*o, key, value)
+}
+
+// OrderedECMAArray EcmaArray representation in Golang which keeps the order of keys
+type OrderedECMAArray []KeyValue
+
+// Get Returns a value of the first pair which has the key
+func (a OrderedECMAArray) Get(key string) (interface{}, bool) {
+	return getKeyValue(a, key)
+}
+
+// Set Replaces a value of the first pair which has the key, or appends the pair if not exists
+func (a *OrderedECMAArray) Set(key string, value interface{}) {
+	*a = setKeyValue(*a, key, value)
+}
+
+func getKeyValue(kvs []KeyValue, key string) (interface{}, bool) {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+func setKeyValue(kvs []KeyValue, key string, value interface{}) []KeyValue {
+	for i := range kvs {
+		if kvs[i].Key == key {
+			kvs[i].Value = value
+			return kvs
+		}
+	}
+	return append(kvs, KeyValue{Key: key, Value: value})
+}
+
+// Date Date representation in Golang which keeps encoded fields as is
+type Date struct {
+	// Millis Milliseconds since the Unix epoch in UTC
+	Millis float64
+	// TZ Time zone offset in minutes east of UTC. It is reserved by the specification and usually 0
+	TZ int16
+}
+
+// XMLDocument XMLDocument representation in Golang
+type XMLDocument string
+
 // ObjectEnd ObjectEnd representation in Golang
 var ObjectEnd = struct{}{}
+
+// UndefinedType A type of Undefined
+type UndefinedType struct{}
+
+// Undefined Undefined representation in Golang
+var Undefined = UndefinedType{}
+
+// TypedObject TypedObject representation in Golang which class name is not registered by RegisterType
+// Fields are decoded into *Value, which keeps encoded representations as is. Fields may also have other values to be encoded.
+type TypedObject struct {
+	ClassName string
+	Fields    map[string]interface{}
+
+	keys []string // an order of keys when decoded
+}
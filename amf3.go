@@ -0,0 +1,437 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/yutopp/go-amf0/internal/shared"
+)
+
+// AMF3Codec The interface implemented by AMF3 codecs which handle values after MarkerAVMPlusObject.
+// Each method must read or write exactly one AMF3 value without the marker. Reference tables of AMF3 are not shared between values.
+// r and w are the Decoder and the Encoder, thus codecs of the amf3 package apply their options, such as WithKeyLess, WithDateRounding, WithOrderedObjects and WithDecoderOptions.
+type AMF3Codec interface {
+	DecodeAMF3(r io.Reader, v interface{}) error
+	EncodeAMF3(w io.Writer, v interface{}) error
+}
+
+// Options which also apply to AMF3 values are passed to the amf3 package through the encoder and the decoder given to codecs
+func init() {
+	shared.EncoderSettingsOf = func(w io.Writer) (shared.EncoderSettings, bool) {
+		enc, ok := w.(*Encoder)
+		if !ok {
+			return shared.EncoderSettings{}, false
+		}
+
+		return shared.EncoderSettings{
+			KeyLess:      enc.keyLess,
+			DateRounding: shared.DateRounding(enc.dateRounding),
+		}, true
+	}
+
+	shared.DecoderSettingsOf = func(r io.Reader) (shared.DecoderSettings, bool) {
+		dec, ok := r.(*Decoder)
+		if !ok {
+			return shared.DecoderSettings{}, false
+		}
+
+		return shared.DecoderSettings{
+			OrderedObjects: dec.useOrderedObjects,
+			Limits:         shared.DecoderOptions(dec.Limits()),
+		}, true
+	}
+}
+
+// AVMPlusObject An AMF3 value which is not decoded. Bytes are the AMF3 value without MarkerAVMPlusObject.
+// Values after MarkerAVMPlusObject are decoded into it when targets are interface{} and no AMF3 codec is specified.
+type AVMPlusObject []byte
+
+// MarshalAMF0 Writes MarkerAVMPlusObject and the AMF3 value as is. nil is written as Null.
+func (o AVMPlusObject) MarshalAMF0(enc *Encoder) error {
+	if o == nil {
+		return enc.encodeNull()
+	}
+
+	if enc.validateRawMessage {
+		if err := validateAMF3(o); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.writeU8(uint8(MarkerAVMPlusObject)); err != nil {
+		return err
+	}
+
+	_, err := enc.Write(o)
+	return err
+}
+
+// UnmarshalAMF0 Captures bytes of the AMF3 value. The next value must be MarkerAVMPlusObject.
+func (o *AVMPlusObject) UnmarshalAMF0(dec *Decoder) error {
+	if o == nil {
+		return fmt.Errorf("AVMPlusObject: UnmarshalAMF0 on nil pointer")
+	}
+
+	marker, err := dec.readU8()
+	if err != nil {
+		return err
+	}
+	if Marker(marker) != MarkerAVMPlusObject {
+		return &UnexpectedMarkerError{
+			Marker: marker,
+		}
+	}
+
+	data, err := dec.readAMF3Raw()
+	if err != nil {
+		return err
+	}
+	*o = append((*o)[0:0], data...)
+
+	return nil
+}
+
+// AMF3Value A value which is encoded in AMF3 after MarkerAVMPlusObject by the codec specified by WithEncodeAMF3
+type AMF3Value struct {
+	Value interface{}
+}
+
+// MarshalAMF0 Writes MarkerAVMPlusObject and the value encoded by the AMF3 codec
+func (v AMF3Value) MarshalAMF0(enc *Encoder) error {
+	if enc.amf3 == nil {
+		return ErrNoAMF3Codec
+	}
+
+	if err := enc.writeU8(uint8(MarkerAVMPlusObject)); err != nil {
+		return err
+	}
+
+	return enc.amf3.EncodeAMF3(enc, v.Value)
+}
+
+func (dec *Decoder) decodeAVMPlusObject(rv reflect.Value) error {
+	rv, err := shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	if dec.amf3 != nil {
+		return dec.amf3.DecodeAMF3(dec, rv.Addr().Interface())
+	}
+
+	if rv.Kind() != reflect.Interface || rv.NumMethod() != 0 {
+		return &NotAssignableError{
+			Message: "Not interface{} for AMF3 values without AMF3 codecs",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	data, err := dec.readAMF3Raw()
+	if err != nil {
+		return err
+	}
+	rv.Set(reflect.ValueOf(AVMPlusObject(data)))
+
+	return nil
+}
+
+// readAMF3Raw Reads bytes of the next AMF3 value
+func (dec *Decoder) readAMF3Raw() ([]byte, error) {
+	return dec.capturing(dec.skipAMF3)
+}
+
+func (dec *Decoder) skipAMF3() error {
+	s := &amf3Skipper{dec: dec}
+	return wrapEOF(s.skipValue()) // The AMF3 value is at the level of the AVMPlus Object
+}
+
+func validateAMF3(data []byte) error {
+	r := bytes.NewReader(data)
+	dec := NewDecoder(r)
+
+	if err := dec.skipAMF3(); err != nil {
+		return &DecodeError{
+			Message: fmt.Sprintf("Invalid AVMPlusObject: %+v", err),
+			Dump:    hex.Dump(data),
+		}
+	}
+	if r.Len() != 0 {
+		return &DecodeError{
+			Message: fmt.Sprintf("Invalid AVMPlusObject: %d bytes remain", r.Len()),
+			Dump:    hex.Dump(data),
+		}
+	}
+
+	return nil
+}
+
+// AMF3 markers which are used to skip values
+const (
+	amf3MarkerUndefined    = 0x00
+	amf3MarkerNull         = 0x01
+	amf3MarkerFalse        = 0x02
+	amf3MarkerTrue         = 0x03
+	amf3MarkerInteger      = 0x04
+	amf3MarkerDouble       = 0x05
+	amf3MarkerString       = 0x06
+	amf3MarkerXMLDocument  = 0x07
+	amf3MarkerDate         = 0x08
+	amf3MarkerArray        = 0x09
+	amf3MarkerObject       = 0x0A
+	amf3MarkerXML          = 0x0B
+	amf3MarkerByteArray    = 0x0C
+	amf3MarkerVectorInt    = 0x0D
+	amf3MarkerVectorUint   = 0x0E
+	amf3MarkerVectorDouble = 0x0F
+	amf3MarkerVectorObject = 0x10
+	amf3MarkerDictionary   = 0x11
+)
+
+// amf3Skipper Skips an AMF3 value. Traits are tracked because the number of members of objects depends on them.
+type amf3Skipper struct {
+	dec    *Decoder
+	traits []amf3Traits
+}
+
+type amf3Traits struct {
+	dynamic    bool
+	numMembers uint32
+}
+
+func (s *amf3Skipper) skip() error {
+	if err := s.dec.enterNest(); err != nil {
+		return err
+	}
+	defer s.dec.leaveNest()
+
+	return s.skipValue()
+}
+
+// skipValue Skips the next AMF3 value at the current nesting level. Children of it are skipped at the next level.
+func (s *amf3Skipper) skipValue() error {
+	marker, err := s.dec.readU8()
+	if err != nil {
+		return err
+	}
+
+	switch marker {
+	case amf3MarkerUndefined, amf3MarkerNull, amf3MarkerFalse, amf3MarkerTrue:
+		return nil // No payloads
+
+	case amf3MarkerInteger:
+		_, err := s.readU29()
+		return err
+
+	case amf3MarkerDouble:
+		return s.dec.discard(8)
+
+	case amf3MarkerString, amf3MarkerXMLDocument, amf3MarkerXML, amf3MarkerByteArray:
+		_, err := s.skipBytes()
+		return err
+
+	case amf3MarkerDate:
+		_, isRef, err := s.readHeader()
+		if err != nil || isRef {
+			return err
+		}
+		return s.dec.discard(8)
+
+	case amf3MarkerArray:
+		count, isRef, err := s.readCount()
+		if err != nil || isRef {
+			return err
+		}
+		if err := s.skipDynamicMembers(); err != nil {
+			return err
+		}
+		return s.skipValues(uint64(count))
+
+	case amf3MarkerObject:
+		return s.skipObject()
+
+	case amf3MarkerVectorInt, amf3MarkerVectorUint, amf3MarkerVectorDouble:
+		count, isRef, err := s.readCount()
+		if err != nil || isRef {
+			return err
+		}
+		size := int64(4)
+		if marker == amf3MarkerVectorDouble {
+			size = 8
+		}
+		return s.dec.discard(1 + int64(count)*size) // fixed-vector flag and items
+
+	case amf3MarkerVectorObject:
+		count, isRef, err := s.readCount()
+		if err != nil || isRef {
+			return err
+		}
+		if err := s.dec.discard(1); err != nil { // fixed-vector flag
+			return err
+		}
+		if _, err := s.skipBytes(); err != nil { // object type name
+			return err
+		}
+		return s.skipValues(uint64(count))
+
+	case amf3MarkerDictionary:
+		count, isRef, err := s.readCount()
+		if err != nil || isRef {
+			return err
+		}
+		if err := s.dec.discard(1); err != nil { // weak-keys flag
+			return err
+		}
+		return s.skipValues(uint64(count) * 2)
+
+	default:
+		return &DecodeError{
+			Message: fmt.Sprintf("Unexpected AMF3 marker: Marker = %+v", marker),
+			Dump:    hex.Dump([]byte{marker}),
+		}
+	}
+}
+
+func (s *amf3Skipper) skipObject() error {
+	u, isRef, err := s.readHeader()
+	if err != nil || isRef {
+		return err
+	}
+
+	var traits amf3Traits
+	switch {
+	case u&0x01 == 0: // A reference to traits
+		index := u >> 1
+		if uint64(index) >= uint64(len(s.traits)) {
+			return &DecodeError{
+				Message: fmt.Sprintf("Out of range AMF3 traits reference: Index = %d, Len = %d", index, len(s.traits)),
+			}
+		}
+		traits = s.traits[index]
+
+	case u&0x02 != 0: // Externalizable
+		return &DecodeError{
+			Message: "Externalizable AMF3 objects cannot be skipped without AMF3 codecs",
+		}
+
+	default:
+		traits = amf3Traits{
+			dynamic:    u&0x04 != 0,
+			numMembers: u >> 3,
+		}
+		if err := s.dec.limiter.CheckObjectKeys(int(traits.numMembers)); err != nil {
+			return err
+		}
+		if _, err := s.skipBytes(); err != nil { // class name
+			return err
+		}
+		for i := uint32(0); i < traits.numMembers; i++ {
+			if _, err := s.skipBytes(); err != nil { // member names
+				return err
+			}
+		}
+		s.traits = append(s.traits, traits)
+	}
+
+	if err := s.skipValues(uint64(traits.numMembers)); err != nil {
+		return err
+	}
+	if traits.dynamic {
+		return s.skipDynamicMembers()
+	}
+
+	return nil
+}
+
+// skipDynamicMembers Skips pairs of names and values until the empty name
+func (s *amf3Skipper) skipDynamicMembers() error {
+	for numKeys := 1; ; numKeys++ {
+		empty, err := s.skipBytes()
+		if err != nil {
+			return err
+		}
+		if empty {
+			return nil
+		}
+
+		if err := s.dec.limiter.CheckObjectKeys(numKeys); err != nil {
+			return err
+		}
+
+		if err := s.skip(); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *amf3Skipper) skipValues(n uint64) error {
+	for i := uint64(0); i < n; i++ {
+		if err := s.skip(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipBytes Skips a string or a byte array which may be a reference. It returns true if the value is an empty inline string.
+func (s *amf3Skipper) skipBytes() (bool, error) {
+	l, isRef, err := s.readHeader()
+	if err != nil || isRef {
+		return false, err
+	}
+
+	return l == 0, s.dec.discardString(l)
+}
+
+// readCount Reads the header of arrays, vectors and dictionaries. Counts of items are checked by limits.
+func (s *amf3Skipper) readCount() (uint32, bool, error) {
+	count, isRef, err := s.readHeader()
+	if err != nil || isRef {
+		return count, isRef, err
+	}
+
+	return count, false, s.dec.limiter.CheckArrayLength(int64(count))
+}
+
+// readHeader Reads U29 which is either a reference or an inline value. The lowest bit is dropped from the returned value.
+func (s *amf3Skipper) readHeader() (uint32, bool, error) {
+	u, err := s.readU29()
+	if err != nil {
+		return 0, false, err
+	}
+
+	return u >> 1, u&0x01 == 0, nil
+}
+
+// readU29 Reads a variable-length unsigned 29-bit integer
+func (s *amf3Skipper) readU29() (uint32, error) {
+	var n uint32
+	for i := 0; i < 3; i++ {
+		b, err := s.dec.readU8()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<7 | uint32(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+
+	b, err := s.dec.readU8()
+	if err != nil {
+		return 0, err
+	}
+
+	return n<<8 | uint32(b), nil
+}
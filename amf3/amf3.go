@@ -0,0 +1,179 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package amf3 AMF3 encoder/decoder which shares struct tags, registered classes and errors with the amf0 package.
+//
+// Fields of structs are resolved by `amf3` tags, and `amf0` tags are used if fields do not have `amf3` tags.
+// Codec plugs this package into values after the avmplus-object marker of AMF0.
+//
+// Codec inherits options of the amf0 package which also apply to AMF3 values, that is, orders of keys, amf0.WithDateRounding,
+// amf0.WithOrderedObjects and amf0.WithDecoderOptions. NewEncoder and NewDecoder accept the same options of this package.
+// amf0.AMF3Value is encoded as its value. Other types which implement amf0.Marshaler or amf0.Unmarshaler, such as amf0.Value and amf0.RawMessage,
+// read and write AMF0, thus they fail to be encoded or decoded in AMF3. Implement Marshaler and Unmarshaler of this package instead.
+// Dates of AMF3 have no time zone fields, thus time.Time values are always decoded in UTC and their sub-millisecond parts are kept as fractions of milliseconds.
+package amf3
+
+import (
+	"reflect"
+
+	"github.com/yutopp/go-amf0"
+	"github.com/yutopp/go-amf0/internal/registry"
+)
+
+// Marker Represents AMF3 value types
+type Marker byte
+
+const (
+	// MarkerUndefined A marker for undefined
+	MarkerUndefined Marker = 0x00
+	// MarkerNull A marker for null
+	MarkerNull Marker = 0x01
+	// MarkerFalse A marker for false
+	MarkerFalse Marker = 0x02
+	// MarkerTrue A marker for true
+	MarkerTrue Marker = 0x03
+	// MarkerInteger A marker for Integer types which are signed 29-bit integers
+	MarkerInteger Marker = 0x04
+	// MarkerDouble A marker for Double types
+	MarkerDouble Marker = 0x05
+	// MarkerString A marker for String types
+	MarkerString Marker = 0x06
+	// MarkerXMLDocument A marker for XMLDocument types which are legacy XML
+	MarkerXMLDocument Marker = 0x07
+	// MarkerDate A marker for Date types
+	MarkerDate Marker = 0x08
+	// MarkerArray A marker for Array types
+	MarkerArray Marker = 0x09
+	// MarkerObject A marker for Object types
+	MarkerObject Marker = 0x0A
+	// MarkerXML A marker for XML types which are E4X
+	MarkerXML Marker = 0x0B
+	// MarkerByteArray A marker for ByteArray types
+	MarkerByteArray Marker = 0x0C
+	// MarkerVectorInt A marker for Vector.<int> types
+	MarkerVectorInt Marker = 0x0D
+	// MarkerVectorUint A marker for Vector.<uint> types
+	MarkerVectorUint Marker = 0x0E
+	// MarkerVectorDouble A marker for Vector.<Number> types
+	MarkerVectorDouble Marker = 0x0F
+	// MarkerVectorObject A marker for Vector.<Object> types
+	MarkerVectorObject Marker = 0x10
+	// MarkerDictionary A marker for Dictionary types
+	MarkerDictionary Marker = 0x11
+)
+
+const (
+	// MinInteger The minimum value of Integer types
+	MinInteger = -1 << 28
+	// MaxInteger The maximum value of Integer types
+	MaxInteger = 1<<28 - 1
+)
+
+// Array Array representation in Golang which has both associative and dense parts
+// Arrays which have only dense parts are decoded into []interface{} when targets are interface{}.
+type Array struct {
+	Assoc map[string]interface{}
+	Dense []interface{}
+}
+
+// XML XML representation in Golang. amf0.XMLDocument is used for XMLDocument types.
+type XML string
+
+// TypedObject Object representation in Golang which class name is not registered by amf0.RegisterType
+type TypedObject struct {
+	ClassName string
+	Fields    map[string]interface{}
+}
+
+// VectorInt Vector.<int> representation in Golang
+type VectorInt []int32
+
+// VectorUint Vector.<uint> representation in Golang
+type VectorUint []uint32
+
+// VectorDouble Vector.<Number> representation in Golang
+type VectorDouble []float64
+
+// VectorObject Vector.<Object> representation in Golang
+type VectorObject struct {
+	TypeName string // A class name of items. It is "*" for any types
+	Fixed    bool
+	Items    []interface{}
+}
+
+// Dictionary Dictionary representation in Golang. Keys can be any values.
+type Dictionary struct {
+	WeakKeys bool
+	Entries  []DictionaryEntry
+}
+
+// DictionaryEntry A pair of a key and a value of Dictionary
+type DictionaryEntry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Externalizable The interface implemented by registered classes which are encoded by themselves as externalizable objects
+// Types must be registered by amf0.RegisterType, and methods must have pointer receivers.
+type Externalizable interface {
+	ReadExternal(dec *Decoder) error
+	WriteExternal(enc *Encoder) error
+}
+
+// ClassNameArrayCollection A class name of ArrayCollection
+const ClassNameArrayCollection = "flex.messaging.io.ArrayCollection"
+
+// ArrayCollection An externalizable class of Flex which wraps an Array
+type ArrayCollection struct {
+	Source []interface{}
+}
+
+// ReadExternal Implements Externalizable
+func (c *ArrayCollection) ReadExternal(dec *Decoder) error {
+	return dec.Decode(&c.Source)
+}
+
+// WriteExternal Implements Externalizable
+func (c *ArrayCollection) WriteExternal(enc *Encoder) error {
+	if c.Source == nil {
+		return enc.Encode([]interface{}{})
+	}
+	return enc.Encode(c.Source)
+}
+
+// builtinClasses Classes which are known only by this package.
+// They are not registered by amf0.RegisterType not to change how the amf0 package decodes Typed Objects of the same names.
+var builtinClasses = map[string]reflect.Type{
+	ClassNameArrayCollection: reflect.TypeOf(&ArrayCollection{}),
+}
+
+// lookupType Returns the type of the class name. Classes registered by amf0.RegisterType have priority over builtin classes.
+func lookupType(className string) (reflect.Type, bool) {
+	if ty, ok := registry.LookupType(className); ok {
+		return ty, true
+	}
+
+	ty, ok := builtinClasses[className]
+	return ty, ok
+}
+
+// lookupClassName Returns the class name of the struct type
+func lookupClassName(ty reflect.Type) (string, bool) {
+	if name, ok := registry.LookupClassName(ty); ok {
+		return name, true
+	}
+
+	for name, builtinTy := range builtinClasses {
+		if builtinTy.Elem() == ty {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Undefined Undefined representation which is shared with the amf0 package
+var Undefined = amf0.Undefined
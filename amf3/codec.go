@@ -0,0 +1,60 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"io"
+
+	"github.com/yutopp/go-amf0"
+	"github.com/yutopp/go-amf0/internal/shared"
+)
+
+// Codec An implementation of amf0.AMF3Codec by this package
+// Specify it by amf0.WithDecodeAMF3 and amf0.WithEncodeAMF3 to handle values after the avmplus-object marker.
+// Options of the amf0.Encoder and the amf0.Decoder which also apply to AMF3 values are inherited, such as amf0.WithKeyLess,
+// amf0.WithDateRounding, amf0.WithOrderedObjects and amf0.WithDecoderOptions.
+type Codec struct {
+	opts []EncoderOption
+}
+
+var _ amf0.AMF3Codec = (*Codec)(nil)
+
+// NewCodec Create a new instance of Codec. Options are applied to encoders which are created for each value after inherited options.
+func NewCodec(opts ...EncoderOption) *Codec {
+	return &Codec{
+		opts: opts,
+	}
+}
+
+// DecodeAMF3 Implements amf0.AMF3Codec
+// Limits which remain in the amf0.Decoder are applied to the AMF3 value.
+func (c *Codec) DecodeAMF3(r io.Reader, v interface{}) error {
+	var opts []DecoderOption
+	if s, ok := shared.DecoderSettingsOf(r); ok {
+		opts = append(opts, func(dec *Decoder) {
+			dec.limiter.Options = s.Limits
+			dec.useOrderedObjects = s.OrderedObjects
+		})
+	}
+
+	return NewDecoder(r, opts...).Decode(v)
+}
+
+// EncodeAMF3 Implements amf0.AMF3Codec
+func (c *Codec) EncodeAMF3(w io.Writer, v interface{}) error {
+	var opts []EncoderOption
+	if s, ok := shared.EncoderSettingsOf(w); ok {
+		opts = append(opts, func(enc *Encoder) {
+			enc.keyLess = s.KeyLess
+			enc.dateRounding = amf0.DateRounding(s.DateRounding)
+		})
+	}
+	opts = append(opts, c.opts...)
+
+	return NewEncoder(w, opts...).Encode(v)
+}
@@ -0,0 +1,224 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+var avmPlusBinary = []byte{
+	// AMF0 Object Marker
+	0x03,
+	// - Key(objectEncoding)
+	0x00, 0x0e, 0x6f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67,
+	// - AMF0 Number(3)
+	0x00, 0x40, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// - Key(point)
+	0x00, 0x05, 0x70, 0x6f, 0x69, 0x6e, 0x74,
+	// - AVMPlusObject Marker
+	0x11,
+	//   AMF3 Object Marker
+	0x0a,
+	//   U29O-traits(2 sealed members, inline)
+	0x23,
+	//   - Class name(Point)
+	0x0b, 0x50, 0x6f, 0x69, 0x6e, 0x74,
+	//   - Member names(x, y)
+	0x03, 0x78, 0x03, 0x79,
+	//   - Integer(1), Integer(2)
+	0x04, 0x01, 0x04, 0x02,
+	// - End
+	0x00, 0x00, 0x09,
+}
+
+func TestCodecDecode(t *testing.T) {
+	t.Run("interface{}", func(t *testing.T) {
+		r := bytes.NewReader(avmPlusBinary)
+		dec := amf0.NewDecoder(r, amf0.WithDecodeAMF3(NewCodec()))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, map[string]interface{}{
+			"objectEncoding": float64(3),
+			"point":          samplePoint{X: 1, Y: 2},
+		}, v)
+		require.Equal(t, 0, r.Len())
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		dec := amf0.NewDecoder(bytes.NewReader(avmPlusBinary), amf0.WithDecodeAMF3(NewCodec()))
+
+		var v struct {
+			ObjectEncoding int          `amf0:"objectEncoding"`
+			Point          *samplePoint `amf0:"point"`
+		}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, 3, v.ObjectEncoding)
+		require.Equal(t, &samplePoint{X: 1, Y: 2}, v.Point)
+	})
+}
+
+func TestCodecDecodeLimits(t *testing.T) {
+	limits := amf0.DecoderOptions{MaxArrayLength: 10, MaxBytes: 64, MaxDepth: 4}
+
+	for _, tc := range []struct {
+		name  string
+		bin   []byte
+		limit string
+	}{
+		{
+			name:  "Vector.<Number> of huge length",
+			bin:   []byte{0x11, 0x0f, 0xff, 0xff, 0xff, 0xff, 0x00}, // AVMPlusObject, Vector.<Number>(length 268435455) without items
+			limit: "MaxArrayLength",
+		},
+		{
+			name: "nested Arrays",
+			bin: []byte{
+				0x0a, 0x00, 0x00, 0x00, 0x01, // AMF0 StrictArray(length 1)
+				0x11,             // - AVMPlusObject
+				0x09, 0x03, 0x01, //   Array(length 1)
+				0x09, 0x03, 0x01, //   - Array(length 1)
+				0x09, 0x03, 0x01, //     - Array(length 1)
+				0x01, //                   - Null
+			},
+			limit: "MaxDepth",
+		},
+		{
+			name:  "ByteArray beyond MaxBytes",
+			bin:   []byte{0x11, 0x0c, 0x81, 0x01}, // AVMPlusObject, ByteArray(length 64) without bytes
+			limit: "MaxBytes",
+		},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			dec := amf0.NewDecoder(bytes.NewReader(tc.bin), amf0.WithDecodeAMF3(NewCodec()), amf0.WithDecoderOptions(limits))
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.IsType(t, &amf0.LimitExceededError{}, err)
+			require.Equal(t, tc.limit, err.(*amf0.LimitExceededError).Limit)
+		})
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		dec := amf0.NewDecoder(bytes.NewReader(avmPlusBinary), amf0.WithDecodeAMF3(NewCodec()), amf0.WithDecoderOptions(limits))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+	})
+}
+
+func TestArrayCollectionInAMF0(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	enc := amf0.NewEncoder(buf)
+	err := enc.Encode(amf0.TypedObject{
+		ClassName: ClassNameArrayCollection,
+		Fields: map[string]interface{}{
+			"source": []interface{}{float64(1)},
+		},
+	})
+	require.Nil(t, err)
+
+	// Builtin classes of this package do not change how amf0 decodes Typed Objects
+	var v interface{}
+	err = amf0.NewDecoder(buf).Decode(&v)
+	require.Nil(t, err)
+	require.IsType(t, amf0.TypedObject{}, v)
+	require.Equal(t, ClassNameArrayCollection, v.(amf0.TypedObject).ClassName)
+	require.Equal(t, map[string]interface{}{
+		"source": &amf0.Value{
+			Kind:     amf0.MarkerStrictArray,
+			Elements: []*amf0.Value{{Kind: amf0.MarkerNumber, Number: 1}},
+		},
+	}, v.(amf0.TypedObject).Fields)
+}
+
+func TestCodecEncode(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	enc := amf0.NewEncoder(buf, amf0.WithEncodeAMF3(NewCodec()), amf0.WithSortedKeys())
+
+	err := enc.Encode(map[string]interface{}{
+		"objectEncoding": 3,
+		"point":          amf0.AMF3Value{Value: samplePoint{X: 1, Y: 2}},
+	})
+	require.Nil(t, err)
+	require.Equal(t, avmPlusBinary, buf.Bytes())
+}
+
+func TestCodecInheritsOptions(t *testing.T) {
+	tm := time.UnixMilli(1000).Add(500 * time.Microsecond)
+
+	t.Run("encoder", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := amf0.NewEncoder(buf,
+			amf0.WithEncodeAMF3(NewCodec()),
+			amf0.WithSortedKeys(),
+			amf0.WithDateRounding(amf0.DateRoundingTruncate),
+		)
+
+		err := enc.Encode(amf0.AMF3Value{Value: map[string]interface{}{"b": 1, "a": tm}})
+		require.Nil(t, err)
+		require.Equal(t, []byte{
+			// AVMPlusObject Marker
+			0x11,
+			// Object Marker
+			0x0a,
+			// U29O-traits(dynamic, inline), Class name(empty)
+			0x0b, 0x01,
+			// - Member name(a), Date(1000) which is truncated
+			0x03, 0x61, 0x08, 0x01, 0x40, 0x8f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00,
+			// - Member name(b), Integer(1)
+			0x03, 0x62, 0x04, 0x01,
+			// - End
+			0x01,
+		}, buf.Bytes())
+	})
+
+	t.Run("encoder without date rounding", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := amf0.NewEncoder(buf, amf0.WithEncodeAMF3(NewCodec()))
+
+		err := enc.Encode(amf0.AMF3Value{Value: tm})
+		require.Error(t, err)
+	})
+
+	t.Run("decoder", func(t *testing.T) {
+		bin := []byte{
+			// AVMPlusObject Marker
+			0x11,
+			// Object Marker
+			0x0a,
+			// U29O-traits(dynamic, inline), Class name(empty)
+			0x0b, 0x01,
+			// - Member name(b), Integer(1)
+			0x03, 0x62, 0x04, 0x01,
+			// - Member name(a), Integer(2)
+			0x03, 0x61, 0x04, 0x02,
+			// - End
+			0x01,
+		}
+		dec := amf0.NewDecoder(bytes.NewReader(bin), amf0.WithDecodeAMF3(NewCodec()), amf0.WithOrderedObjects())
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, amf0.OrderedObject{
+			{Key: "b", Value: 1},
+			{Key: "a", Value: 2},
+		}, v)
+	})
+}
@@ -0,0 +1,1208 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yutopp/go-amf0"
+	"github.com/yutopp/go-amf0/internal/shared"
+	"github.com/yutopp/go-amf0/internal/structfields"
+)
+
+// Decoder Read from the reader and decode AMF3 into objects in Golang
+// It reads exactly bytes of values, thus values which follow them can be read from the reader.
+type Decoder struct {
+	r io.Reader
+
+	strings []string
+	refs    shared.References
+	traits  []*traits
+
+	peekedU8  uint8
+	hasPeeked bool
+
+	limiter shared.Limiter // counts a nesting level and bytes which are read since the decoder is created or reset
+
+	useOrderedObjects bool
+}
+
+// traits Traits of objects which are shared by references
+type traits struct {
+	className      string
+	names          []string // names of sealed members
+	dynamic        bool
+	externalizable bool
+}
+
+// Unmarshaler The interface implemented by types which can decode AMF3 into themselves
+// UnmarshalAMF3 must read exactly one value by methods of the decoder, such as Decode and Read.
+type Unmarshaler interface {
+	UnmarshalAMF3(dec *Decoder) error
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	amf0UnmarshalerType = reflect.TypeOf((*amf0.Unmarshaler)(nil)).Elem()
+)
+
+// DecoderOption An option for Decoder
+type DecoderOption func(*Decoder)
+
+// WithDecoderOptions Limit resources which are consumed by the decoder. Limits are the same as ones of amf0.DecoderOptions.
+// MaxArrayLength limits elements of Arrays, Vectors and Dictionaries, and MaxObjectKeys limits sealed and dynamic members of each Object.
+func WithDecoderOptions(o amf0.DecoderOptions) DecoderOption {
+	return func(dec *Decoder) {
+		dec.limiter.Options = shared.DecoderOptions(o)
+	}
+}
+
+// WithOrderedObjects Decode Objects which have no class names into amf0.OrderedObject instead of maps when targets are interface{}.
+// It is the same as amf0.WithOrderedObjects.
+func WithOrderedObjects() DecoderOption {
+	return func(dec *Decoder) {
+		dec.useOrderedObjects = true
+	}
+}
+
+// NewDecoder Create a new instance of Decoder
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	dec := &Decoder{
+		r: r,
+	}
+	for _, opt := range opts {
+		opt(dec)
+	}
+
+	return dec
+}
+
+// Decode Decode a value into the object. The object must be a non-nil pointer.
+func (dec *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	return dec.decode(rv)
+}
+
+// Read Read raw bytes from the reader. It is intended to be used by Unmarshaler implementations to read raw AMF3 values.
+func (dec *Decoder) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if dec.hasPeeked {
+		p[0] = dec.peekedU8
+		dec.hasPeeked = false
+		return 1, nil
+	}
+
+	p, err := dec.limiter.LimitRead(p)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := dec.r.Read(p)
+	dec.limiter.NumBytes += int64(n)
+
+	return n, err
+}
+
+// Reset Reset a state of the decoder
+// References are resolved within values decoded between resets, thus Reset should be called for each message
+func (dec *Decoder) Reset(r io.Reader) {
+	dec.r = r
+	dec.strings = nil
+	dec.refs = nil
+	dec.traits = nil
+	dec.hasPeeked = false
+	dec.limiter.NumBytes = 0
+}
+
+func (dec *Decoder) decode(rv reflect.Value) error {
+	if u, ok := dec.unmarshalerOf(rv); ok {
+		return u.UnmarshalAMF3(dec)
+	}
+	if err := checkAMF0Unmarshaler(rv); err != nil {
+		return err
+	}
+
+	if err := dec.enterNest(); err != nil {
+		return err
+	}
+	defer dec.leaveNest()
+
+	marker, err := dec.readU8()
+	if err != nil {
+		return err
+	}
+
+	switch Marker(marker) {
+	case MarkerUndefined:
+		return shared.SetNothing(rv, amf0.Undefined)
+
+	case MarkerNull:
+		return shared.SetNothing(rv, nil)
+
+	case MarkerFalse:
+		return shared.SetBool(rv, false)
+
+	case MarkerTrue:
+		return shared.SetBool(rv, true)
+
+	case MarkerInteger:
+		return dec.decodeInteger(rv)
+
+	case MarkerDouble:
+		return dec.decodeDouble(rv)
+
+	case MarkerString:
+		return dec.decodeString(rv)
+
+	case MarkerXMLDocument, MarkerXML:
+		return dec.decodeXML(Marker(marker), rv)
+
+	case MarkerDate:
+		return dec.decodeDate(rv)
+
+	case MarkerArray:
+		return dec.decodeArray(rv)
+
+	case MarkerObject:
+		return dec.decodeObject(rv)
+
+	case MarkerByteArray:
+		return dec.decodeByteArray(rv)
+
+	case MarkerVectorInt, MarkerVectorUint, MarkerVectorDouble:
+		return dec.decodeVector(Marker(marker), rv)
+
+	case MarkerVectorObject:
+		return dec.decodeVectorObject(rv)
+
+	case MarkerDictionary:
+		return dec.decodeDictionary(rv)
+
+	default:
+		return &amf0.UnexpectedMarkerError{
+			Marker: marker,
+		}
+	}
+}
+
+// unmarshalerOf Returns Unmarshaler if the pointer or the nested pointer implements it. Nil pointers are allocated on the way.
+// If the Unmarshaler is in nested pointers and the next value is Null or Undefined, the pointer will be set to nil instead.
+func (dec *Decoder) unmarshalerOf(rv reflect.Value) (Unmarshaler, bool) {
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, false
+	}
+
+	depth := 0
+	for ty := rv.Type(); !ty.Implements(unmarshalerType); ty = ty.Elem() {
+		if ty.Elem().Kind() != reflect.Ptr {
+			return nil, false
+		}
+		depth++
+	}
+
+	if depth > 0 {
+		marker, err := dec.peekU8()
+		if err != nil || Marker(marker) == MarkerNull || Marker(marker) == MarkerUndefined {
+			return nil, false
+		}
+	}
+
+	for i := 0; i < depth; i++ {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Type().Elem().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	return rv.Interface().(Unmarshaler), true
+}
+
+// checkAMF0Unmarshaler Rejects values which can decode only AMF0 by amf0.Unmarshaler, such as amf0.Value and amf0.RawMessage
+func checkAMF0Unmarshaler(rv reflect.Value) error {
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil
+	}
+
+	for ty := rv.Type(); ty.Kind() == reflect.Ptr; ty = ty.Elem() {
+		if ty.Implements(amf0UnmarshalerType) {
+			return &amf0.NotAssignableError{
+				Message: "amf0.Unmarshaler cannot decode AMF3 values, implement Unmarshaler of the amf3 package",
+				Kind:    ty.Elem().Kind(),
+				Type:    ty.Elem(),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (dec *Decoder) decodeInteger(rv reflect.Value) error {
+	u, err := dec.readU29()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	n := int(int32(u<<3) >> 3) // Sign extension of 29 bits
+
+	return shared.SetNumber(rv, reflect.ValueOf(n))
+}
+
+func (dec *Decoder) decodeDouble(rv reflect.Value) error {
+	d, err := dec.readDouble()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	return shared.SetNumber(rv, reflect.ValueOf(d))
+}
+
+func (dec *Decoder) decodeString(rv reflect.Value) error {
+	str, err := dec.readUTF8VR()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	return shared.SetString(rv, str)
+}
+
+func (dec *Decoder) decodeXML(marker Marker, rv reflect.Value) error {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(l, rv)
+	}
+
+	str, err := dec.readUTF8Chars(l)
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	var v reflect.Value
+	if marker == MarkerXML {
+		v = reflect.ValueOf(XML(str))
+	} else {
+		v = reflect.ValueOf(amf0.XMLDocument(str))
+	}
+	dec.refs.Add(v)
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	if rv.Kind() == reflect.Interface {
+		rv.Set(v)
+		return nil
+	}
+
+	return shared.SetString(rv, str)
+}
+
+func (dec *Decoder) decodeDate(rv reflect.Value) error {
+	index, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(index, rv)
+	}
+
+	unixMs, err := dec.readDouble()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	if rv.Type() == reflect.TypeOf(amf0.Date{}) {
+		rv.Set(reflect.ValueOf(amf0.Date{
+			Millis: unixMs,
+		}))
+		dec.refs.Add(rv)
+		return nil
+	}
+
+	if rv.Kind() != reflect.Interface && rv.Type() != reflect.TypeOf(time.Time{}) {
+		return &amf0.NotAssignableError{
+			Message: "Not time.Time or amf0.Date type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	t, err := shared.MillisToTime(unixMs)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(t)
+	dec.refs.Add(v)
+	rv.Set(v)
+
+	return nil
+}
+
+func (dec *Decoder) decodeArray(rv reflect.Value) error {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(l, rv)
+	}
+
+	// Each element has at least a marker
+	if err := dec.checkArrayLength(l, 1); err != nil {
+		return err
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		// Dense parts are stored with keys of indexes
+		if err := shared.CheckMapKeyType(rv.Type().Key()); err != nil {
+			return err
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		dec.refs.Add(rv)
+
+		if err := dec.decodeDynamicMembers(rv, 0); err != nil {
+			return err
+		}
+		for i := 0; i < l; i++ {
+			if err := dec.decodeMapValue(rv, strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice {
+			rv.Set(reflect.MakeSlice(rv.Type(), l, l))
+		}
+		if rv.Len() != l {
+			return fmt.Errorf("length of array is different: Expected = %d, Actual = %d", l, rv.Len())
+		}
+		dec.refs.Add(rv)
+
+		// Associative parts are discarded
+		var assoc map[string]interface{}
+		if err := dec.decodeDynamicMembers(reflect.ValueOf(&assoc).Elem(), 0); err != nil {
+			return err
+		}
+
+		return dec.decodeElements(rv)
+
+	case reflect.Interface, reflect.Struct:
+		if rv.Kind() == reflect.Struct && rv.Type() != reflect.TypeOf(Array{}) {
+			break
+		}
+
+		// The type depends on associative parts, thus the value is registered after they are decoded
+		index := dec.refs.Reserve()
+
+		assoc := make(map[string]interface{})
+		if err := dec.decodeDynamicMembers(reflect.ValueOf(assoc), 0); err != nil {
+			return err
+		}
+
+		dense := reflect.ValueOf(make([]interface{}, l))
+		if len(assoc) > 0 || rv.Kind() == reflect.Struct {
+			a := reflect.New(reflect.TypeOf(Array{})).Elem()
+			a.Field(0).Set(reflect.ValueOf(assoc))
+			a.Field(1).Set(dense)
+			dec.refs[index] = a
+			rv.Set(a)
+		} else {
+			dec.refs[index] = dense
+			rv.Set(dense)
+		}
+
+		return dec.decodeElements(dense)
+	}
+
+	return &amf0.NotAssignableError{
+		Message: "Not array or slice or map or interface type",
+		Kind:    rv.Kind(),
+		Type:    rv.Type(),
+	}
+}
+
+func (dec *Decoder) decodeElements(rv reflect.Value) error {
+	for i := 0; i < rv.Len(); i++ {
+		if err := dec.decode(rv.Index(i).Addr()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dec *Decoder) decodeObject(rv reflect.Value) error {
+	u, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(u, rv)
+	}
+
+	t, err := dec.readTraits(u)
+	if err != nil {
+		return err
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	if t.externalizable {
+		return dec.decodeExternalizable(t.className, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if t.className == "" {
+			if dec.useOrderedObjects {
+				return dec.decodeOrderedObject(t, rv)
+			}
+			rv.Set(reflect.MakeMap(reflect.TypeOf(map[string]interface{}{})))
+			rv = rv.Elem()
+			break
+		}
+
+		ty, ok := lookupType(t.className)
+		if !ok {
+			to := reflect.New(reflect.TypeOf(TypedObject{})).Elem()
+			if err := dec.decodeTypedObject(t, to); err != nil {
+				return err
+			}
+			rv.Set(to)
+			return nil
+		}
+
+		isPtr := ty.Kind() == reflect.Ptr
+		if isPtr {
+			ty = ty.Elem()
+		}
+
+		v := reflect.New(ty)
+		if err := dec.decodeMembers(t, v.Elem()); err != nil {
+			return err
+		}
+
+		if isPtr {
+			rv.Set(v)
+		} else {
+			rv.Set(v.Elem())
+		}
+		return nil
+
+	case reflect.Map:
+		if err := shared.CheckMapKeyType(rv.Type().Key()); err != nil {
+			return err
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+	case reflect.Struct:
+		if rv.Type() == reflect.TypeOf(TypedObject{}) {
+			return dec.decodeTypedObject(t, rv)
+		}
+
+	case reflect.Slice:
+		if rv.Type() == reflect.TypeOf(amf0.OrderedObject{}) {
+			return dec.decodeOrderedObject(t, rv)
+		}
+		return &amf0.NotAssignableError{
+			Message: "Not map or struct or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: "Not map or struct or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return dec.decodeMembers(t, rv)
+}
+
+func (dec *Decoder) decodeTypedObject(t *traits, rv reflect.Value) error {
+	to := rv.Addr().Interface().(*TypedObject)
+	to.ClassName = t.className
+	to.Fields = make(map[string]interface{})
+
+	return dec.decodeMembers(t, reflect.ValueOf(to.Fields))
+}
+
+// decodeOrderedObject Decodes sealed and dynamic members into amf0.OrderedObject in the order.
+// The value is registered as a reference after them because members are appended to it.
+func (dec *Decoder) decodeOrderedObject(t *traits, rv reflect.Value) error {
+	index := dec.refs.Reserve()
+
+	o := reflect.New(reflect.TypeOf(amf0.OrderedObject{})).Elem()
+	for _, name := range t.names {
+		if err := dec.decodeMember(o, name); err != nil {
+			return err
+		}
+	}
+	if t.dynamic {
+		if err := dec.decodeDynamicMembers(o, len(t.names)); err != nil {
+			return err
+		}
+	}
+
+	dec.refs[index] = o
+	rv.Set(o)
+
+	return nil
+}
+
+// decodeMembers Decodes sealed and dynamic members into the map or the struct. The value is registered as a reference before them.
+func (dec *Decoder) decodeMembers(t *traits, rv reflect.Value) error {
+	dec.refs.Add(rv)
+
+	for _, name := range t.names {
+		if err := dec.decodeMember(rv, name); err != nil {
+			return err
+		}
+	}
+
+	if t.dynamic {
+		return dec.decodeDynamicMembers(rv, len(t.names))
+	}
+
+	return nil
+}
+
+// decodeDynamicMembers Decodes pairs of names and values until the empty name. numKeys is the number of members which are already decoded.
+func (dec *Decoder) decodeDynamicMembers(rv reflect.Value, numKeys int) error {
+	for {
+		name, err := dec.readUTF8VR()
+		if err != nil {
+			return wrapEOF(err)
+		}
+		if name == "" {
+			return nil
+		}
+
+		numKeys++
+		if err := dec.limiter.CheckObjectKeys(numKeys); err != nil {
+			return err
+		}
+
+		if err := dec.decodeMember(rv, name); err != nil {
+			return err
+		}
+	}
+}
+
+func (dec *Decoder) decodeMember(rv reflect.Value, name string) error {
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		return dec.decodeMapValue(rv, name)
+
+	case reflect.Struct:
+		f, ok := cachedTypeFields(rv.Type()).ByName[name]
+		if !ok {
+			// discard
+			var null interface{}
+			return dec.decode(reflect.ValueOf(&null))
+		}
+
+		v, ok := structfields.ByIndexAlloc(rv, f.Index)
+		if !ok {
+			return &amf0.NotAssignableError{
+				Message: "Embedded pointer to unexported struct",
+				Kind:    v.Kind(),
+				Type:    v.Type(),
+			}
+		}
+		if f.AsString {
+			return dec.decodeStringified(v)
+		}
+
+		return dec.decode(v.Addr())
+
+	case reflect.Slice: // amf0.OrderedObject
+		var v interface{}
+		if err := dec.decode(reflect.ValueOf(&v)); err != nil {
+			return err
+		}
+		rv.Set(reflect.Append(rv, reflect.ValueOf(amf0.KeyValue{Key: name, Value: v})))
+		return nil
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: "Not map or struct type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+}
+
+func (dec *Decoder) decodeMapValue(rv reflect.Value, key string) error {
+	k, err := shared.MapKey(rv.Type().Key(), key)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.New(rv.Type().Elem())
+	if err := dec.decode(v); err != nil {
+		return err
+	}
+	rv.SetMapIndex(k, v.Elem())
+
+	return nil
+}
+
+// decodeStringified Decodes a String into a boolean or numeric value
+func (dec *Decoder) decodeStringified(rv reflect.Value) error {
+	var s string
+	if err := dec.decode(reflect.ValueOf(&s)); err != nil {
+		return err
+	}
+
+	return shared.SetStringified(rv, s)
+}
+
+// decodeExternalizable Decodes the object by ReadExternal of the registered class
+func (dec *Decoder) decodeExternalizable(className string, rv reflect.Value) error {
+	ty, ok := lookupType(className)
+	if !ok {
+		return &amf0.DecodeError{
+			Message: fmt.Sprintf("Externalizable class is not registered: ClassName = %s", className),
+		}
+	}
+
+	isPtr := ty.Kind() == reflect.Ptr
+	if isPtr {
+		ty = ty.Elem()
+	}
+	if !reflect.PtrTo(ty).Implements(externalizableType) {
+		return &amf0.DecodeError{
+			Message: fmt.Sprintf("Registered class is not Externalizable: ClassName = %s, Type = %s", className, ty),
+		}
+	}
+
+	var v reflect.Value
+	switch {
+	case rv.Kind() == reflect.Interface && rv.NumMethod() == 0:
+		v = reflect.New(ty)
+		if isPtr {
+			rv.Set(v)
+		} else {
+			defer func() { rv.Set(v.Elem()) }()
+		}
+
+	case rv.Type() == ty:
+		v = rv.Addr()
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: fmt.Sprintf("Not interface{} or %s type", ty),
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	dec.refs.Add(v.Elem())
+
+	return v.Interface().(Externalizable).ReadExternal(dec)
+}
+
+func (dec *Decoder) decodeByteArray(rv reflect.Value) error {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(l, rv)
+	}
+
+	// ByteArrays are limited as strings
+	if err := dec.limiter.CheckStringLength(int64(l)); err != nil {
+		return err
+	}
+	if err := dec.limiter.CheckRemainingBytes(int64(l)); err != nil {
+		return err
+	}
+
+	data := make([]byte, l)
+	if _, err := io.ReadFull(dec, data); err != nil {
+		return wrapEOF(err)
+	}
+	v := reflect.ValueOf(data)
+	dec.refs.Add(v)
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	if rv.Kind() != reflect.Interface {
+		if u, ok := rv.Addr().Interface().(encoding.BinaryUnmarshaler); ok {
+			return u.UnmarshalBinary(data)
+		}
+	}
+
+	switch {
+	case rv.Kind() == reflect.Interface:
+		rv.Set(v)
+
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		rv.SetBytes(data)
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: "Not byte slice or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// decodeVector Decodes vectors of numbers into VectorInt, VectorUint, VectorDouble or slices of numbers
+func (dec *Decoder) decodeVector(marker Marker, rv reflect.Value) error {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(l, rv)
+	}
+
+	if _, err := dec.readU8(); err != nil { // fixed-vector flag
+		return wrapEOF(err)
+	}
+
+	size := 4 // int32 and uint32
+	if marker == MarkerVectorDouble {
+		size = 8
+	}
+	if err := dec.checkArrayLength(l, size); err != nil {
+		return err
+	}
+
+	var items interface{}
+	switch marker {
+	case MarkerVectorInt:
+		items = make(VectorInt, l)
+	case MarkerVectorUint:
+		items = make(VectorUint, l)
+	case MarkerVectorDouble:
+		items = make(VectorDouble, l)
+	}
+	if err := binary.Read(dec, binary.BigEndian, items); err != nil {
+		return wrapEOF(err)
+	}
+	v := reflect.ValueOf(items)
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(v)
+
+	case reflect.Slice:
+		switch rv.Type().Elem().Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Float32, reflect.Float64:
+		default:
+			return &amf0.NotAssignableError{
+				Message: "Not numeric slice type",
+				Kind:    rv.Kind(),
+				Type:    rv.Type(),
+			}
+		}
+
+		rv.Set(reflect.MakeSlice(rv.Type(), l, l))
+		for i := 0; i < l; i++ {
+			rv.Index(i).Set(v.Index(i).Convert(rv.Type().Elem()))
+		}
+		v = rv
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: "Not slice or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+	dec.refs.Add(v)
+
+	return nil
+}
+
+func (dec *Decoder) decodeVectorObject(rv reflect.Value) error {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(l, rv)
+	}
+
+	// Each element has at least a marker
+	if err := dec.checkArrayLength(l, 1); err != nil {
+		return err
+	}
+
+	fixed, err := dec.readU8()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	typeName, err := dec.readUTF8VR()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rv.Kind() == reflect.Interface && rv.NumMethod() == 0, rv.Type() == reflect.TypeOf(VectorObject{}):
+		v := reflect.New(reflect.TypeOf(VectorObject{})).Elem()
+		vo := v.Addr().Interface().(*VectorObject)
+		vo.TypeName = typeName
+		vo.Fixed = fixed != 0
+		vo.Items = make([]interface{}, l)
+		dec.refs.Add(v)
+		rv.Set(v)
+
+		return dec.decodeElements(reflect.ValueOf(vo.Items))
+
+	case rv.Kind() == reflect.Slice:
+		rv.Set(reflect.MakeSlice(rv.Type(), l, l))
+		dec.refs.Add(rv)
+
+		return dec.decodeElements(rv)
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: "Not slice or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+}
+
+func (dec *Decoder) decodeDictionary(rv reflect.Value) error {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return wrapEOF(err)
+	}
+	if isRef {
+		return dec.decodeReference(l, rv)
+	}
+
+	// Each entry has at least markers of the key and the value
+	if err := dec.checkArrayLength(l, 2); err != nil {
+		return err
+	}
+
+	weakKeys, err := dec.readU8()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case rv.Kind() == reflect.Interface && rv.NumMethod() == 0, rv.Type() == reflect.TypeOf(Dictionary{}):
+		// Entries grow while decoding, thus the value is registered after they are decoded
+		index := dec.refs.Reserve()
+
+		d := Dictionary{
+			WeakKeys: weakKeys != 0,
+			Entries:  make([]DictionaryEntry, l),
+		}
+		for i := range d.Entries {
+			if err := dec.Decode(&d.Entries[i].Key); err != nil {
+				return err
+			}
+			if err := dec.Decode(&d.Entries[i].Value); err != nil {
+				return err
+			}
+		}
+
+		v := reflect.ValueOf(d)
+		dec.refs[index] = v
+		rv.Set(v)
+
+		return nil
+
+	case rv.Kind() == reflect.Map:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		dec.refs.Add(rv)
+
+		for i := 0; i < l; i++ {
+			k := reflect.New(rv.Type().Key())
+			if err := dec.decode(k); err != nil {
+				return err
+			}
+			v := reflect.New(rv.Type().Elem())
+			if err := dec.decode(v); err != nil {
+				return err
+			}
+			rv.SetMapIndex(k.Elem(), v.Elem())
+		}
+
+		return nil
+
+	default:
+		return &amf0.NotAssignableError{
+			Message: "Not map or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+}
+
+func (dec *Decoder) decodeReference(index int, rv reflect.Value) error {
+	return dec.refs.Assign(index, rv)
+}
+
+// readTraits Reads inline traits or a reference to traits. u is the header of objects without the lowest bit.
+func (dec *Decoder) readTraits(u int) (*traits, error) {
+	if u&0x01 == 0 { // A reference to traits
+		index := u >> 1
+		if index >= len(dec.traits) {
+			return nil, &amf0.DecodeError{
+				Message: fmt.Sprintf("Traits reference index is out of range: Index = %d, Length = %d", index, len(dec.traits)),
+			}
+		}
+		return dec.traits[index], nil
+	}
+
+	t := &traits{
+		externalizable: u&0x02 != 0,
+		dynamic:        u&0x04 != 0,
+	}
+
+	className, err := dec.readUTF8VR()
+	if err != nil {
+		return nil, wrapEOF(err)
+	}
+	t.className = className
+
+	if !t.externalizable {
+		// Each name has at least a header
+		if err := dec.limiter.CheckObjectKeys(u >> 3); err != nil {
+			return nil, err
+		}
+		if err := dec.limiter.CheckRemainingBytes(int64(u >> 3)); err != nil {
+			return nil, err
+		}
+
+		t.names = make([]string, u>>3)
+		for i := range t.names {
+			name, err := dec.readUTF8VR()
+			if err != nil {
+				return nil, wrapEOF(err)
+			}
+			t.names[i] = name
+		}
+	}
+	dec.traits = append(dec.traits, t)
+
+	return t, nil
+}
+
+// enterNest Increments the nesting level of values. leaveNest must be called when the value is decoded.
+func (dec *Decoder) enterNest() error {
+	dec.limiter.Nesting++
+	return dec.limiter.CheckDepth(dec.limiter.Nesting)
+}
+
+func (dec *Decoder) leaveNest() {
+	dec.limiter.Nesting--
+}
+
+// checkArrayLength Checks the number of elements, and that elements which have at least size bytes can be read within MaxBytes
+func (dec *Decoder) checkArrayLength(length int, size int) error {
+	if err := dec.limiter.CheckArrayLength(int64(length)); err != nil {
+		return err
+	}
+
+	return dec.limiter.CheckRemainingBytes(int64(length) * int64(size))
+}
+
+func (dec *Decoder) peekU8() (uint8, error) {
+	if !dec.hasPeeked {
+		u8 := make([]byte, 1)
+		if _, err := io.ReadFull(dec, u8); err != nil {
+			return 0, err
+		}
+		dec.peekedU8 = u8[0]
+		dec.hasPeeked = true
+	}
+
+	return dec.peekedU8, nil
+}
+
+func (dec *Decoder) readU8() (uint8, error) {
+	u8 := make([]byte, 1)
+	if _, err := io.ReadFull(dec, u8); err != nil {
+		return 0, err
+	}
+
+	return u8[0], nil
+}
+
+func (dec *Decoder) readDouble() (float64, error) {
+	d := make([]byte, 8)
+	if _, err := io.ReadFull(dec, d); err != nil {
+		return 0, err
+	}
+
+	bits := binary.BigEndian.Uint64(d)
+	return math.Float64frombits(bits), nil
+}
+
+// readU29 Reads a variable-length unsigned 29-bit integer
+func (dec *Decoder) readU29() (uint32, error) {
+	var n uint32
+	for i := 0; i < 3; i++ {
+		b, err := dec.readU8()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<7 | uint32(b&0x7f)
+		if b&0x80 == 0 {
+			return n, nil
+		}
+	}
+
+	b, err := dec.readU8()
+	if err != nil {
+		return 0, err
+	}
+
+	return n<<8 | uint32(b), nil
+}
+
+// readHeader Reads U29 which is either a reference or an inline value. The lowest bit is dropped from the returned value.
+func (dec *Decoder) readHeader() (int, bool, error) {
+	u, err := dec.readU29()
+	if err != nil {
+		return 0, false, err
+	}
+
+	return int(u >> 1), u&0x01 == 0, nil
+}
+
+func (dec *Decoder) readUTF8Chars(l int) (string, error) {
+	if err := dec.limiter.CheckStringLength(int64(l)); err != nil {
+		return "", err
+	}
+	if err := dec.limiter.CheckRemainingBytes(int64(l)); err != nil {
+		return "", err
+	}
+
+	str := make([]byte, l)
+	if _, err := io.ReadFull(dec, str); err != nil {
+		return "", err
+	}
+
+	if !utf8.Valid(str) {
+		return "", &amf0.DecodeError{
+			Message: "Invalid utf8 sequence",
+			Dump:    hex.Dump(str),
+		}
+	}
+
+	return string(str), nil
+}
+
+// readUTF8VR Reads a string or a reference to the string which is already read
+func (dec *Decoder) readUTF8VR() (string, error) {
+	l, isRef, err := dec.readHeader()
+	if err != nil {
+		return "", err
+	}
+
+	if isRef {
+		if l >= len(dec.strings) {
+			return "", &amf0.DecodeError{
+				Message: fmt.Sprintf("String reference index is out of range: Index = %d, Length = %d", l, len(dec.strings)),
+			}
+		}
+		return dec.strings[l], nil
+	}
+
+	if l == 0 {
+		return "", nil // Empty strings are not added into the table
+	}
+
+	str, err := dec.readUTF8Chars(l)
+	if err != nil {
+		return "", err
+	}
+	dec.strings = append(dec.strings, str)
+
+	return str, nil
+}
+
+func wrapEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,405 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func TestDecodeCommon(t *testing.T) {
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(tc.Binary)
+			dec := NewDecoder(r)
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.NoError(t, err)
+			if tc.AssumeNil {
+				require.Nil(t, v)
+			} else {
+				require.Equal(t, tc.Value, v)
+			}
+
+			require.Equal(t, 0, r.Len()) // Assure that all bytes are consumed
+		})
+	}
+}
+
+func TestDecodeStruct(t *testing.T) {
+	t.Run("sealed", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{
+			0x0a,                               // Object Marker
+			0x23,                               // U29O-traits(2 sealed members, inline)
+			0x0b, 0x50, 0x6f, 0x69, 0x6e, 0x74, // Class name(Point)
+			0x03, 0x78, 0x03, 0x79, // Member names(x, y)
+			0x04, 0x01, 0x04, 0x02, // Integer(1), Integer(2)
+		}))
+
+		var v samplePoint
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, samplePoint{X: 1, Y: 2}, v)
+	})
+
+	t.Run("dynamic", func(t *testing.T) {
+		var v struct {
+			A string `amf3:"a"`
+			B int    `amf0:"b,string"`
+		}
+
+		dec := NewDecoder(bytes.NewReader([]byte{
+			0x0a,       // Object Marker
+			0x0b,       // U29O-traits(0 sealed members, dynamic, inline)
+			0x01,       // Class name(empty)
+			0x03, 0x61, // Key(a)
+			0x06, 0x03, 0x78, // String(x)
+			0x03, 0x62, // Key(b)
+			0x06, 0x03, 0x31, // String(1)
+			0x03, 0x63, // Key(c)
+			0x03, // True (discarded)
+			0x01, // End of dynamic members
+		}))
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, "x", v.A)
+		require.Equal(t, 1, v.B)
+	})
+}
+
+func TestDecodeReference(t *testing.T) {
+	t.Run("shared map", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(sharedObjectBinary))
+
+		var v []map[string]interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Len(t, v, 2)
+
+		v[0]["b"] = 2
+		require.Equal(t, 2, v[1]["b"]) // Shared
+	})
+
+	t.Run("shared pointer", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(sharedObjectBinary))
+
+		var v []*struct {
+			A int `amf3:"a"`
+		}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Len(t, v, 2)
+		require.True(t, v[0] == v[1])
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x0a, 0x02})) // Object(reference 1)
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.IsType(t, &amf0.DecodeError{}, err)
+	})
+}
+
+func TestDecodeNumber(t *testing.T) {
+	t.Run("Integer into float64", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x04, 0x7f}))
+
+		var v float64
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, float64(127), v)
+	})
+
+	t.Run("Double into int", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x05, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}))
+
+		var v int
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, 1, v)
+	})
+
+	t.Run("not numeric", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x04, 0x7f}))
+
+		var v string
+		err := dec.Decode(&v)
+		require.IsType(t, &amf0.NotAssignableError{}, err)
+	})
+}
+
+func TestDecodeNotEmptyInterface(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bin  []byte
+	}{
+		{
+			name: "Vector.<Object>",
+			bin:  []byte{0x10, 0x03, 0x01, 0x03, 0x2a, 0x06, 0x03, 0x61}, // Vector.<Object>(*, ["a"])
+		},
+		{
+			name: "Dictionary",
+			bin:  []byte{0x11, 0x03, 0x00, 0x04, 0x01, 0x06, 0x03, 0x61}, // Dictionary({1: "a"})
+		},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tc.bin))
+
+			var v fmt.Stringer
+			err := dec.Decode(&v)
+			require.IsType(t, &amf0.NotAssignableError{}, err)
+		})
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		bin    []byte
+		opts   amf0.DecoderOptions
+		limit  string
+		within amf0.DecoderOptions
+	}{
+		{
+			name:   "MaxDepth",
+			bin:    []byte{0x09, 0x03, 0x01, 0x09, 0x03, 0x01, 0x01}, // Array(length 1), - Array(length 1), - Null
+			opts:   amf0.DecoderOptions{MaxDepth: 2},
+			limit:  "MaxDepth",
+			within: amf0.DecoderOptions{MaxDepth: 3},
+		},
+		{
+			name:  "MaxArrayLength(Array)",
+			bin:   []byte{0x09, 0x8f, 0x51}, // Array(length 1000) without elements
+			opts:  amf0.DecoderOptions{MaxArrayLength: 100},
+			limit: "MaxArrayLength",
+		},
+		{
+			name:  "MaxArrayLength(Vector.<int>)",
+			bin:   []byte{0x0d, 0x8f, 0x51, 0x00}, // Vector.<int>(length 1000) without items
+			opts:  amf0.DecoderOptions{MaxArrayLength: 100},
+			limit: "MaxArrayLength",
+		},
+		{
+			name:  "MaxArrayLength(Vector.<Object>)",
+			bin:   []byte{0x10, 0x8f, 0x51, 0x00, 0x03, 0x2a}, // Vector.<Object>(length 1000, *) without items
+			opts:  amf0.DecoderOptions{MaxArrayLength: 100},
+			limit: "MaxArrayLength",
+		},
+		{
+			name:  "MaxArrayLength(Dictionary)",
+			bin:   []byte{0x11, 0x8f, 0x51, 0x00}, // Dictionary(length 1000) without entries
+			opts:  amf0.DecoderOptions{MaxArrayLength: 100},
+			limit: "MaxArrayLength",
+		},
+		{
+			name:  "MaxStringLength(String)",
+			bin:   []byte{0x06, 0x8f, 0x51}, // String(length 1000) without bytes
+			opts:  amf0.DecoderOptions{MaxStringLength: 100},
+			limit: "MaxStringLength",
+		},
+		{
+			name:  "MaxStringLength(ByteArray)",
+			bin:   []byte{0x0c, 0x8f, 0x51}, // ByteArray(length 1000) without bytes
+			opts:  amf0.DecoderOptions{MaxStringLength: 100},
+			limit: "MaxStringLength",
+		},
+		{
+			name:   "MaxObjectKeys(sealed)",
+			bin:    []byte{0x0a, 0x23, 0x0b, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x03, 0x78, 0x03, 0x79, 0x04, 0x01, 0x04, 0x02}, // Point{x: 1, y: 2}
+			opts:   amf0.DecoderOptions{MaxObjectKeys: 1},
+			limit:  "MaxObjectKeys",
+			within: amf0.DecoderOptions{MaxObjectKeys: 2},
+		},
+		{
+			name:   "MaxObjectKeys(dynamic)",
+			bin:    []byte{0x0a, 0x0b, 0x01, 0x03, 0x61, 0x01, 0x03, 0x62, 0x01, 0x01}, // {a: null, b: null}
+			opts:   amf0.DecoderOptions{MaxObjectKeys: 1},
+			limit:  "MaxObjectKeys",
+			within: amf0.DecoderOptions{MaxObjectKeys: 2},
+		},
+		{
+			name:  "MaxBytes",
+			bin:   []byte{0x0f, 0x8f, 0x51, 0x00}, // Vector.<Number>(length 1000) without items
+			opts:  amf0.DecoderOptions{MaxBytes: 1024},
+			limit: "MaxBytes",
+		},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tc.bin), WithDecoderOptions(tc.opts))
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.IsType(t, &amf0.LimitExceededError{}, err)
+			require.Equal(t, tc.limit, err.(*amf0.LimitExceededError).Limit)
+		})
+
+		if tc.within != (amf0.DecoderOptions{}) {
+			t.Run(tc.name+" within limits", func(t *testing.T) {
+				dec := NewDecoder(bytes.NewReader(tc.bin), WithDecoderOptions(tc.within))
+
+				var v interface{}
+				err := dec.Decode(&v)
+				require.Nil(t, err)
+			})
+		}
+	}
+}
+
+func TestDecodeExact(t *testing.T) {
+	r := bytes.NewReader([]byte{
+		0x06, 0x03, 0x61, // String(a)
+		0xff, // A byte which is not a part of the value
+	})
+	dec := NewDecoder(r)
+
+	var v string
+	err := dec.Decode(&v)
+	require.Nil(t, err)
+	require.Equal(t, "a", v)
+	require.Equal(t, 1, r.Len())
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bin  []byte
+		err  interface{}
+	}{
+		{
+			name: "unknown marker",
+			bin:  []byte{0x20},
+			err:  &amf0.UnexpectedMarkerError{},
+		},
+		{
+			name: "traits reference out of range",
+			bin:  []byte{0x0a, 0x05}, // Object Marker, U29O-traits-ref(1)
+			err:  &amf0.DecodeError{},
+		},
+		{
+			name: "string reference out of range",
+			bin:  []byte{0x06, 0x00}, // String(reference 0)
+			err:  &amf0.DecodeError{},
+		},
+		{
+			name: "unregistered externalizable",
+			bin:  []byte{0x0a, 0x07, 0x03, 0x61}, // Object Marker, U29O-traits-ext, Class name(a)
+			err:  &amf0.DecodeError{},
+		},
+		{
+			name: "not externalizable",
+			bin:  []byte{0x0a, 0x07, 0x0b, 0x50, 0x6f, 0x69, 0x6e, 0x74}, // Object Marker, U29O-traits-ext, Class name(Point)
+			err:  &amf0.DecodeError{},
+		},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tc.bin))
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.IsType(t, tc.err, err)
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x06, 0x0b, 0x68})) // String(length 5) with 1 byte
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+}
+
+func TestDecodeOrderedObjects(t *testing.T) {
+	bin := []byte{
+		// Array Marker
+		0x09,
+		// U29A(2 dense elements), End of associative parts
+		0x05, 0x01,
+		// - Object Marker
+		0x0a,
+		//   U29O-traits(1 sealed member, dynamic, inline), Class name(empty), Member name(b)
+		0x1b, 0x01, 0x03, 0x62,
+		//   - Integer(1)
+		0x04, 0x01,
+		//   - Member name(a), Integer(2)
+		0x03, 0x61, 0x04, 0x02,
+		//   - End
+		0x01,
+		// - Object(reference 1)
+		0x0a, 0x02,
+	}
+	expected := amf0.OrderedObject{
+		{Key: "b", Value: 1},
+		{Key: "a", Value: 2},
+	}
+
+	t.Run("interface{}", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(bin), WithOrderedObjects())
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, []interface{}{expected, expected}, v)
+	})
+
+	t.Run("amf0.OrderedObject", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(bin))
+
+		var v []amf0.OrderedObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, []amf0.OrderedObject{expected, expected}, v)
+	})
+
+	t.Run("without options", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(bin))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		m := map[string]interface{}{"b": 1, "a": 2}
+		require.Equal(t, []interface{}{m, m}, v)
+	})
+}
+
+func TestDecodeAMF0Unmarshaler(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "amf0.Value", v: &amf0.Value{}},
+		{name: "amf0.RawMessage", v: &amf0.RawMessage{}},
+		{name: "nested pointer", v: new(*amf0.Value)},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader([]byte{0x04, 0x01}))
+
+			err := dec.Decode(tc.v)
+			require.IsType(t, &amf0.NotAssignableError{}, err)
+		})
+	}
+}
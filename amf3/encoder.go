@@ -0,0 +1,774 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yutopp/go-amf0"
+	"github.com/yutopp/go-amf0/internal/shared"
+	"github.com/yutopp/go-amf0/internal/structfields"
+)
+
+// Encoder Encode objects in Golang into AMF3 and writes to the writer
+// Strings, complex values and traits which are already encoded are written as references.
+type Encoder struct {
+	w            io.Writer
+	keyLess      func(a, b string) bool
+	dateRounding amf0.DateRounding
+
+	strings map[string]int
+	refs    map[shared.ReferenceKey]int
+	markers []Marker // markers of complex values which are indexed by the object reference table
+	traits  map[string]int
+}
+
+// Marshaler The interface implemented by types which can encode themselves into AMF3
+// MarshalAMF3 must write exactly one value by methods of the encoder, such as Encode and Write.
+type Marshaler interface {
+	MarshalAMF3(enc *Encoder) error
+}
+
+var (
+	marshalerType       = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	amf0MarshalerType   = reflect.TypeOf((*amf0.Marshaler)(nil)).Elem()
+	externalizableType  = reflect.TypeOf((*Externalizable)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// EncoderOption An option for Encoder
+type EncoderOption func(*Encoder)
+
+// WithSortedKeys Encode keys of maps in lexicographical order to make outputs deterministic
+func WithSortedKeys() EncoderOption {
+	return WithKeyLess(func(a, b string) bool {
+		return a < b
+	})
+}
+
+// WithKeyPriority Encode the specified keys of maps first in the specified order, and then the other keys in lexicographical order
+func WithKeyPriority(keys ...string) EncoderOption {
+	return WithKeyLess(shared.KeyPriority(keys...))
+}
+
+// WithKeyLess Encode keys of maps in the order defined by the less function
+func WithKeyLess(less func(a, b string) bool) EncoderOption {
+	return func(enc *Encoder) {
+		enc.keyLess = less
+	}
+}
+
+// WithDateRounding Specify the policy to encode sub-millisecond parts of time.Time values. It is the same as amf0.WithDateRounding.
+func WithDateRounding(r amf0.DateRounding) EncoderOption {
+	return func(enc *Encoder) {
+		enc.dateRounding = r
+	}
+}
+
+// NewEncoder Create a new instance of Encoder
+func NewEncoder(w io.Writer, opts ...EncoderOption) *Encoder {
+	enc := &Encoder{
+		w: w,
+	}
+	for _, opt := range opts {
+		opt(enc)
+	}
+
+	return enc
+}
+
+// Encode Encode objects
+func (enc *Encoder) Encode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	return enc.encode(rv)
+}
+
+// Write Write raw bytes to the writer. It is intended to be used by Marshaler implementations to write raw AMF3 values.
+func (enc *Encoder) Write(p []byte) (int, error) {
+	return enc.w.Write(p)
+}
+
+// Reset Reset a state of the encoder
+// References are resolved within values encoded between resets, thus Reset should be called for each message
+func (enc *Encoder) Reset(w io.Writer) {
+	enc.w = w
+	enc.strings = nil
+	enc.refs = nil
+	enc.markers = nil
+	enc.traits = nil
+}
+
+func (enc *Encoder) encode(rv reflect.Value) error {
+	key, ok := shared.ReferenceKeyOf(rv)
+	if !ok {
+		return enc.encodeValue(rv)
+	}
+
+	if index, ok := enc.refs[key]; ok {
+		return enc.encodeReference(index)
+	}
+
+	// Register the value before encoding children to support cyclic values
+	index := len(enc.markers)
+	if enc.refs == nil {
+		enc.refs = make(map[shared.ReferenceKey]int)
+	}
+	enc.refs[key] = index
+
+	if err := enc.encodeValue(rv); err != nil {
+		return err
+	}
+
+	if len(enc.markers) == index {
+		// The value was not encoded as a complex object, thus it cannot be referenced
+		delete(enc.refs, key)
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeValue(rv reflect.Value) error {
+	if m, ok := shared.InterfaceOf(rv, marshalerType); ok {
+		return m.(Marshaler).MarshalAMF3(enc)
+	}
+	if m, ok := shared.InterfaceOf(rv, amf0MarshalerType); ok {
+		if v, ok := m.(amf0.AMF3Value); ok {
+			return enc.encode(reflect.ValueOf(v.Value)) // Already in AMF3
+		}
+		return fmt.Errorf("amf0.Marshaler cannot encode AMF3 values, implement Marshaler of the amf3 package: Type = %s", rv.Type())
+	}
+
+	// time.Time implements encoding.TextMarshaler, however it should be encoded as Date.
+	// Pointers are checked after they are dereferenced not to encode *time.Time as String.
+	if rv.IsValid() && rv.Kind() != reflect.Ptr && rv.Type() != reflect.TypeOf(time.Time{}) {
+		if m, ok := shared.InterfaceOf(rv, textMarshalerType); ok {
+			text, err := m.(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return err
+			}
+			return enc.encodeString(string(text))
+		}
+
+		if m, ok := shared.InterfaceOf(rv, binaryMarshalerType); ok {
+			data, err := m.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				return err
+			}
+			return enc.encodeByteArray(data)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return enc.encode(rv.Elem())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := rv.Int(); n >= MinInteger && n <= MaxInteger {
+			return enc.encodeInteger(int32(n))
+		}
+		return enc.encodeDouble(float64(rv.Int()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n := rv.Uint(); n <= MaxInteger {
+			return enc.encodeInteger(int32(n))
+		}
+		return enc.encodeDouble(float64(rv.Uint()))
+
+	case reflect.Float32, reflect.Float64:
+		return enc.encodeDouble(rv.Float())
+
+	case reflect.Bool:
+		if rv.Bool() {
+			return enc.writeMarker(MarkerTrue)
+		}
+		return enc.writeMarker(MarkerFalse)
+
+	case reflect.String:
+		switch rv.Type() {
+		case reflect.TypeOf(XML("")):
+			return enc.encodeXML(MarkerXML, rv.String())
+		case reflect.TypeOf(amf0.XMLDocument("")):
+			return enc.encodeXML(MarkerXMLDocument, rv.String())
+		}
+		return enc.encodeString(rv.String())
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return enc.writeMarker(MarkerNull)
+		}
+		if !shared.IsStringKey(rv.Type().Key()) {
+			return enc.encodeMapAsDictionary(rv)
+		}
+		members, err := enc.mapMembers(rv)
+		if err != nil {
+			return err
+		}
+		if rv.Type() == reflect.TypeOf(amf0.ECMAArray{}) {
+			return enc.encodeArray(members, reflect.Value{})
+		}
+		return enc.encodeDynamicObject("", members)
+
+	case reflect.Array, reflect.Slice:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return enc.writeMarker(MarkerNull)
+		}
+		switch rv.Type() {
+		case reflect.TypeOf(amf0.OrderedObject{}):
+			return enc.encodeDynamicObject("", orderedMembers(rv))
+		case reflect.TypeOf(amf0.OrderedECMAArray{}):
+			return enc.encodeArray(orderedMembers(rv), reflect.Value{})
+		case reflect.TypeOf(VectorInt{}):
+			return enc.encodeVectorInt(rv.Interface().(VectorInt))
+		case reflect.TypeOf(VectorUint{}):
+			return enc.encodeVectorUint(rv.Interface().(VectorUint))
+		case reflect.TypeOf(VectorDouble{}):
+			return enc.encodeVectorDouble(rv.Interface().(VectorDouble))
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return enc.encodeByteArray(data)
+		}
+		return enc.encodeArray(nil, rv)
+
+	case reflect.Interface:
+		if rv.IsNil() {
+			return enc.writeMarker(MarkerNull)
+		}
+		return enc.encode(rv.Elem())
+
+	case reflect.Invalid:
+		return enc.writeMarker(MarkerNull)
+
+	case reflect.Struct:
+		switch rv.Type() {
+		case reflect.TypeOf(amf0.Undefined):
+			return enc.writeMarker(MarkerUndefined)
+		case reflect.TypeOf(time.Time{}):
+			ms, err := shared.TimeToMillis(rv.Interface().(time.Time), shared.DateRounding(enc.dateRounding))
+			if err != nil {
+				return err
+			}
+			return enc.encodeDate(ms)
+		case reflect.TypeOf(amf0.Date{}):
+			return enc.encodeDate(rv.Interface().(amf0.Date).Millis)
+		case reflect.TypeOf(Array{}):
+			a := rv.Interface().(Array)
+			members, err := enc.mapMembers(reflect.ValueOf(a.Assoc))
+			if err != nil {
+				return err
+			}
+			return enc.encodeArray(members, reflect.ValueOf(a.Dense))
+		case reflect.TypeOf(TypedObject{}):
+			to := rv.Interface().(TypedObject)
+			members, err := enc.mapMembers(reflect.ValueOf(to.Fields))
+			if err != nil {
+				return err
+			}
+			return enc.encodeDynamicObject(to.ClassName, members)
+		case reflect.TypeOf(amf0.TypedObject{}):
+			to := rv.Interface().(amf0.TypedObject)
+			members, err := enc.mapMembers(reflect.ValueOf(to.Fields))
+			if err != nil {
+				return err
+			}
+			return enc.encodeDynamicObject(to.ClassName, members)
+		case reflect.TypeOf(VectorObject{}):
+			return enc.encodeVectorObject(rv.Interface().(VectorObject))
+		case reflect.TypeOf(Dictionary{}):
+			return enc.encodeDictionary(rv.Interface().(Dictionary))
+		default:
+			className, _ := lookupClassName(rv.Type())
+			if className != "" {
+				if m, ok := shared.InterfaceOf(rv, externalizableType); ok {
+					return enc.encodeExternalizable(className, m.(Externalizable))
+				}
+			}
+			return enc.encodeStruct(className, rv)
+		}
+
+	default:
+		return &amf0.UnexpectedValueError{
+			Kind: rv.Kind(),
+		}
+	}
+}
+
+// mapMembers Returns members of the map which has string keys. They are sorted if the key order is specified.
+func (enc *Encoder) mapMembers(rv reflect.Value) ([]shared.MapEntry, error) {
+	return shared.MapEntries(rv, enc.keyLess)
+}
+
+// orderedMembers Returns members of amf0.OrderedObject or amf0.OrderedECMAArray in the order
+func orderedMembers(rv reflect.Value) []shared.MapEntry {
+	members := make([]shared.MapEntry, rv.Len())
+	for i := range members {
+		kv := rv.Index(i)
+		members[i] = shared.MapEntry{
+			Name:  kv.FieldByName("Key").String(),
+			Value: kv.FieldByName("Value"),
+		}
+	}
+
+	return members
+}
+
+func (enc *Encoder) encodeReference(index int) error {
+	if err := enc.writeMarker(enc.markers[index]); err != nil {
+		return err
+	}
+
+	return enc.writeU29(uint32(index) << 1)
+}
+
+func (enc *Encoder) encodeInteger(n int32) error {
+	if err := enc.writeMarker(MarkerInteger); err != nil {
+		return err
+	}
+
+	return enc.writeU29(uint32(n) & 0x1fffffff)
+}
+
+func (enc *Encoder) encodeDouble(d float64) error {
+	if err := enc.writeMarker(MarkerDouble); err != nil {
+		return err
+	}
+
+	return enc.writeDouble(d)
+}
+
+func (enc *Encoder) encodeString(s string) error {
+	if err := enc.writeMarker(MarkerString); err != nil {
+		return err
+	}
+
+	return enc.writeUTF8VR(s)
+}
+
+func (enc *Encoder) encodeXML(marker Marker, s string) error {
+	if err := enc.beginObject(marker); err != nil {
+		return err
+	}
+
+	if err := enc.writeInlineLength(len(s)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(enc.w, s)
+	return err
+}
+
+func (enc *Encoder) encodeDate(ms float64) error {
+	if err := enc.beginObject(MarkerDate); err != nil {
+		return err
+	}
+
+	if err := enc.writeU29(0x01); err != nil { // Not a reference
+		return err
+	}
+
+	return enc.writeDouble(ms)
+}
+
+func (enc *Encoder) encodeByteArray(data []byte) error {
+	if err := enc.beginObject(MarkerByteArray); err != nil {
+		return err
+	}
+
+	if err := enc.writeInlineLength(len(data)); err != nil {
+		return err
+	}
+
+	_, err := enc.w.Write(data)
+	return err
+}
+
+// encodeArray Encodes an Array which has the associative part and the dense part. dense must be a slice, an array or an invalid value.
+func (enc *Encoder) encodeArray(assoc []shared.MapEntry, dense reflect.Value) error {
+	if err := enc.beginObject(MarkerArray); err != nil {
+		return err
+	}
+
+	l := 0
+	if dense.IsValid() {
+		l = dense.Len()
+	}
+	if err := enc.writeInlineLength(l); err != nil {
+		return err
+	}
+
+	if err := enc.writeDynamicMembers(assoc); err != nil {
+		return err
+	}
+
+	for i := 0; i < l; i++ {
+		if err := enc.encode(dense.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeDynamicObject Encodes an object which has only dynamic members
+func (enc *Encoder) encodeDynamicObject(className string, members []shared.MapEntry) error {
+	if err := enc.beginObject(MarkerObject); err != nil {
+		return err
+	}
+
+	if err := enc.writeTraits(className, nil, true); err != nil {
+		return err
+	}
+
+	return enc.writeDynamicMembers(members)
+}
+
+// writeDynamicMembers Writes pairs of names and values followed by the empty name
+func (enc *Encoder) writeDynamicMembers(members []shared.MapEntry) error {
+	for _, m := range members {
+		if m.Name == "" {
+			return fmt.Errorf("name of dynamic members must not be empty")
+		}
+
+		if err := enc.writeUTF8VR(m.Name); err != nil {
+			return err
+		}
+
+		if err := enc.encode(m.Value); err != nil {
+			return err
+		}
+	}
+
+	return enc.writeUTF8VR("") // The end of dynamic members
+}
+
+// encodeStruct Encodes the struct as an object which has only sealed members
+func (enc *Encoder) encodeStruct(className string, rv reflect.Value) error {
+	if err := enc.beginObject(MarkerObject); err != nil {
+		return err
+	}
+
+	type sealed struct {
+		field *structfields.Field
+		value reflect.Value
+	}
+
+	fields := cachedTypeFields(rv.Type())
+	members := make([]sealed, 0, len(fields.List))
+	names := make([]string, 0, len(fields.List))
+	for i := range fields.List {
+		f := &fields.List[i]
+
+		value, ok := structfields.ByIndex(rv, f.Index)
+		if !ok {
+			continue
+		}
+		if f.OmitEmpty && structfields.IsEmptyValue(value) {
+			continue
+		}
+
+		members = append(members, sealed{field: f, value: value})
+		names = append(names, f.Name)
+	}
+
+	if err := enc.writeTraits(className, names, false); err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		if m.field.AsString {
+			if err := enc.encodeStringified(m.value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := enc.encode(m.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeStringified Encodes a boolean or numeric value as a String
+func (enc *Encoder) encodeStringified(rv reflect.Value) error {
+	s, err := shared.FormatStringified(rv)
+	if err != nil {
+		return err
+	}
+
+	return enc.encodeString(s)
+}
+
+func (enc *Encoder) encodeExternalizable(className string, ext Externalizable) error {
+	if err := enc.beginObject(MarkerObject); err != nil {
+		return err
+	}
+
+	key := "+" + className // Externalizable traits have only class names
+	if index, ok := enc.traits[key]; ok {
+		if err := enc.writeU29(uint32(index)<<2 | 0x01); err != nil {
+			return err
+		}
+	} else {
+		enc.addTraits(key)
+		if err := enc.writeU29(0x07); err != nil { // Inline externalizable traits
+			return err
+		}
+		if err := enc.writeUTF8VR(className); err != nil {
+			return err
+		}
+	}
+
+	return ext.WriteExternal(enc)
+}
+
+func (enc *Encoder) encodeVectorInt(v VectorInt) error {
+	if err := enc.beginVector(MarkerVectorInt, len(v), false); err != nil {
+		return err
+	}
+
+	return binary.Write(enc.w, binary.BigEndian, []int32(v))
+}
+
+func (enc *Encoder) encodeVectorUint(v VectorUint) error {
+	if err := enc.beginVector(MarkerVectorUint, len(v), false); err != nil {
+		return err
+	}
+
+	return binary.Write(enc.w, binary.BigEndian, []uint32(v))
+}
+
+func (enc *Encoder) encodeVectorDouble(v VectorDouble) error {
+	if err := enc.beginVector(MarkerVectorDouble, len(v), false); err != nil {
+		return err
+	}
+
+	return binary.Write(enc.w, binary.BigEndian, []float64(v))
+}
+
+func (enc *Encoder) encodeVectorObject(v VectorObject) error {
+	if err := enc.beginVector(MarkerVectorObject, len(v.Items), v.Fixed); err != nil {
+		return err
+	}
+
+	typeName := v.TypeName
+	if typeName == "" {
+		typeName = "*"
+	}
+	if err := enc.writeUTF8VR(typeName); err != nil {
+		return err
+	}
+
+	for _, item := range v.Items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// beginVector Writes the header of vectors which has the length and the fixed-vector flag
+func (enc *Encoder) beginVector(marker Marker, l int, fixed bool) error {
+	if err := enc.beginObject(marker); err != nil {
+		return err
+	}
+
+	if err := enc.writeInlineLength(l); err != nil {
+		return err
+	}
+
+	return enc.writeFlag(fixed)
+}
+
+func (enc *Encoder) encodeDictionary(d Dictionary) error {
+	if err := enc.beginObject(MarkerDictionary); err != nil {
+		return err
+	}
+
+	if err := enc.writeInlineLength(len(d.Entries)); err != nil {
+		return err
+	}
+
+	if err := enc.writeFlag(d.WeakKeys); err != nil {
+		return err
+	}
+
+	for _, e := range d.Entries {
+		if err := enc.Encode(e.Key); err != nil {
+			return err
+		}
+		if err := enc.Encode(e.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeMapAsDictionary Encodes the map which keys are not strings as a Dictionary
+func (enc *Encoder) encodeMapAsDictionary(rv reflect.Value) error {
+	if err := enc.beginObject(MarkerDictionary); err != nil {
+		return err
+	}
+
+	if err := enc.writeInlineLength(rv.Len()); err != nil {
+		return err
+	}
+
+	if err := enc.writeFlag(false); err != nil { // Not weak keys
+		return err
+	}
+
+	iter := rv.MapRange()
+	for iter.Next() {
+		if err := enc.encode(iter.Key()); err != nil {
+			return err
+		}
+		if err := enc.encode(iter.Value()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// beginObject Writes the marker of the complex value and adds it into the object reference table
+func (enc *Encoder) beginObject(marker Marker) error {
+	if err := enc.writeMarker(marker); err != nil {
+		return err
+	}
+	enc.markers = append(enc.markers, marker)
+
+	return nil
+}
+
+// writeTraits Writes inline traits or a reference to traits which are already written
+func (enc *Encoder) writeTraits(className string, names []string, dynamic bool) error {
+	key := className + "\x00" + strings.Join(names, "\x00")
+	if dynamic {
+		key = "*" + key
+	}
+
+	if index, ok := enc.traits[key]; ok {
+		return enc.writeU29(uint32(index)<<2 | 0x01)
+	}
+	enc.addTraits(key)
+
+	u := uint32(len(names))<<4 | 0x03
+	if dynamic {
+		u |= 0x08
+	}
+	if err := enc.writeU29(u); err != nil {
+		return err
+	}
+
+	if err := enc.writeUTF8VR(className); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := enc.writeUTF8VR(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *Encoder) addTraits(key string) {
+	if enc.traits == nil {
+		enc.traits = make(map[string]int)
+	}
+	enc.traits[key] = len(enc.traits)
+}
+
+func (enc *Encoder) writeMarker(marker Marker) error {
+	_, err := enc.w.Write([]byte{byte(marker)})
+	return err
+}
+
+func (enc *Encoder) writeFlag(b bool) error {
+	u8 := []byte{0x00}
+	if b {
+		u8[0] = 0x01
+	}
+
+	_, err := enc.w.Write(u8)
+	return err
+}
+
+func (enc *Encoder) writeDouble(d float64) error {
+	bits := math.Float64bits(d)
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, bits)
+
+	_, err := enc.w.Write(b)
+	return err
+}
+
+// writeU29 Writes a variable-length unsigned 29-bit integer
+func (enc *Encoder) writeU29(u uint32) error {
+	var b []byte
+	switch {
+	case u < 0x80:
+		b = []byte{byte(u)}
+	case u < 0x4000:
+		b = []byte{byte(u>>7 | 0x80), byte(u & 0x7f)}
+	case u < 0x200000:
+		b = []byte{byte(u>>14 | 0x80), byte(u>>7 | 0x80), byte(u & 0x7f)}
+	case u < 0x20000000:
+		b = []byte{byte(u>>22 | 0x80), byte(u>>15 | 0x80), byte(u>>8 | 0x80), byte(u)}
+	default:
+		return fmt.Errorf("out of range of U29: Value = %d", u)
+	}
+
+	_, err := enc.w.Write(b)
+	return err
+}
+
+// writeInlineLength Writes the length of the inline value
+func (enc *Encoder) writeInlineLength(l int) error {
+	if l > MaxInteger {
+		return fmt.Errorf("unsupported length: Expected <= %d, Actual = %d", MaxInteger, l)
+	}
+
+	return enc.writeU29(uint32(l)<<1 | 0x01)
+}
+
+// writeUTF8VR Writes a string or a reference to the string which is already written. Empty strings are never referenced.
+func (enc *Encoder) writeUTF8VR(s string) error {
+	if s == "" {
+		return enc.writeU29(0x01)
+	}
+
+	if index, ok := enc.strings[s]; ok {
+		return enc.writeU29(uint32(index) << 1)
+	}
+	if enc.strings == nil {
+		enc.strings = make(map[string]int)
+	}
+	enc.strings[s] = len(enc.strings)
+
+	if err := enc.writeInlineLength(len(s)); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(enc.w, s)
+	return err
+}
@@ -0,0 +1,221 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func TestEncodeCommon(t *testing.T) {
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBuffer([]byte{})
+			enc := NewEncoder(buf, WithSortedKeys()) // for debuging
+
+			err := enc.Encode(tc.Value)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+func TestEncodeOutOfIntegerRange(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	enc := NewEncoder(buf)
+
+	err := enc.Encode(MaxInteger + 1)
+	require.Nil(t, err)
+	require.Equal(t, []byte{
+		// Double Marker
+		0x05,
+		// Value(268435456: double) BigEndian
+		0x41, 0xb0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, buf.Bytes())
+}
+
+func TestEncodeReference(t *testing.T) {
+	t.Run("shared map", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		obj := map[string]interface{}{"a": 1}
+		err := enc.Encode([]interface{}{obj, obj})
+		require.Nil(t, err)
+		require.Equal(t, sharedObjectBinary, buf.Bytes())
+	})
+
+	t.Run("cyclic", func(t *testing.T) {
+		type node struct {
+			Next *node `amf3:"next"`
+		}
+		n := &node{}
+		n.Next = n
+
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(n)
+		require.Nil(t, err)
+		require.Equal(t, []byte{
+			// Object Marker
+			0x0a,
+			// U29O-traits(1 sealed member, inline)
+			0x13,
+			// - Class name(empty)
+			0x01,
+			// - Member name(next)
+			0x09, 0x6e, 0x65, 0x78, 0x74,
+			// - Object(reference 0)
+			0x0a, 0x00,
+		}, buf.Bytes())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode("a")
+		require.Nil(t, err)
+
+		enc.Reset(buf)
+		err = enc.Encode("a")
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x06, 0x03, 0x61, 0x06, 0x03, 0x61}, buf.Bytes())
+	})
+}
+
+func TestEncodeUnsupportedTypes(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	enc := NewEncoder(buf)
+
+	ch := make(chan int) // cannot encode
+	err := enc.Encode(ch)
+	require.Error(t, err)
+}
+
+func TestEncodeTimePointer(t *testing.T) {
+	tm := time.UnixMilli(1000).In(time.UTC)
+	want := []byte{
+		// Date Marker
+		0x08,
+		// U29D(inline)
+		0x01,
+		// Value(1000: double) BigEndian
+		0x40, 0x8f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	t.Run("pointer", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(&tm)
+		require.Nil(t, err)
+		require.Equal(t, want, buf.Bytes())
+	})
+
+	t.Run("field", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(struct {
+			T *time.Time `amf3:"t"`
+		}{T: &tm})
+		require.Nil(t, err)
+
+		expected := []byte{
+			0x0a,       // Object Marker
+			0x13,       // U29O-traits(1 sealed member, inline)
+			0x01,       // Class name(empty)
+			0x03, 0x74, // Member name(t)
+		}
+		require.Equal(t, append(expected, want...), buf.Bytes())
+	})
+}
+
+func TestEncodeDateRounding(t *testing.T) {
+	tm := time.UnixMilli(1000).Add(500 * time.Microsecond)
+
+	t.Run("reject by default", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(tm)
+		require.Error(t, err)
+	})
+
+	t.Run("fraction", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithDateRounding(amf0.DateRoundingFraction))
+
+		err := enc.Encode(tm)
+		require.Nil(t, err)
+		require.Equal(t, []byte{
+			// Date Marker
+			0x08,
+			// U29D(inline)
+			0x01,
+			// Value(1000.5: double) BigEndian
+			0x40, 0x8f, 0x44, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}, buf.Bytes())
+	})
+}
+
+func TestEncodeKeyPriority(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	enc := NewEncoder(buf, WithKeyPriority("b"))
+
+	err := enc.Encode(map[string]interface{}{"a": 1, "b": 2})
+	require.Nil(t, err)
+	require.Equal(t, []byte{
+		// Object Marker
+		0x0a,
+		// U29O-traits(dynamic, inline), Class name(empty)
+		0x0b, 0x01,
+		// - Member name(b), Integer(2)
+		0x03, 0x62, 0x04, 0x02,
+		// - Member name(a), Integer(1)
+		0x03, 0x61, 0x04, 0x01,
+		// - End
+		0x01,
+	}, buf.Bytes())
+}
+
+func TestEncodeAMF0Marshaler(t *testing.T) {
+	t.Run("amf0.AMF3Value", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode([]interface{}{amf0.AMF3Value{Value: 1}})
+		require.Nil(t, err)
+		require.Equal(t, []byte{
+			// Array Marker
+			0x09,
+			// U29A(1 dense element), End of associative parts
+			0x03, 0x01,
+			// - Integer(1)
+			0x04, 0x01,
+		}, buf.Bytes())
+	})
+
+	t.Run("amf0.RawMessage", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(amf0.RawMessage{0x05})
+		require.Error(t, err)
+	})
+}
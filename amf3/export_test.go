@@ -0,0 +1,484 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"time"
+
+	"github.com/yutopp/go-amf0"
+)
+
+type testCase struct {
+	Name      string
+	Value     interface{}
+	Binary    []byte
+	AssumeNil bool
+}
+
+// samplePoint A registered class which fields have both amf3 and amf0 tags
+type samplePoint struct {
+	X int `amf3:"x"`
+	Y int `amf0:"y"`
+}
+
+func init() {
+	amf0.RegisterType("Point", samplePoint{})
+}
+
+var testCases = []testCase{
+	{
+		Name:  "Undefined",
+		Value: amf0.Undefined,
+		Binary: []byte{
+			// Undefined Marker
+			0x00,
+		},
+	},
+	{
+		Name:  "Null",
+		Value: nil,
+		Binary: []byte{
+			// Null Marker
+			0x01,
+		},
+		AssumeNil: true,
+	},
+	{
+		Name:  "False",
+		Value: false,
+		Binary: []byte{
+			// False Marker
+			0x02,
+		},
+	},
+	{
+		Name:  "True",
+		Value: true,
+		Binary: []byte{
+			// True Marker
+			0x03,
+		},
+	},
+	{
+		Name:  "Integer(1 byte)",
+		Value: 127,
+		Binary: []byte{
+			// Integer Marker
+			0x04,
+			// U29(127)
+			0x7f,
+		},
+	},
+	{
+		Name:  "Integer(2 bytes)",
+		Value: 128,
+		Binary: []byte{
+			// Integer Marker
+			0x04,
+			// U29(128)
+			0x81, 0x00,
+		},
+	},
+	{
+		Name:  "Integer(3 bytes)",
+		Value: 16384,
+		Binary: []byte{
+			// Integer Marker
+			0x04,
+			// U29(16384)
+			0x81, 0x80, 0x00,
+		},
+	},
+	{
+		Name:  "Integer(max)",
+		Value: MaxInteger,
+		Binary: []byte{
+			// Integer Marker
+			0x04,
+			// U29(268435455)
+			0xbf, 0xff, 0xff, 0xff,
+		},
+	},
+	{
+		Name:  "Integer(-1)",
+		Value: -1,
+		Binary: []byte{
+			// Integer Marker
+			0x04,
+			// U29(0x1fffffff)
+			0xff, 0xff, 0xff, 0xff,
+		},
+	},
+	{
+		Name:  "Integer(min)",
+		Value: MinInteger,
+		Binary: []byte{
+			// Integer Marker
+			0x04,
+			// U29(0x10000000)
+			0xc0, 0x80, 0x80, 0x00,
+		},
+	},
+	{
+		Name:  "Double",
+		Value: 1.5,
+		Binary: []byte{
+			// Double Marker
+			0x05,
+			// Value(1.5: double) BigEndian
+			0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
+	{
+		Name:  "String",
+		Value: "hello",
+		Binary: []byte{
+			// String Marker
+			0x06,
+			// U29S(length 5, inline)
+			0x0b,
+			// Value(hello: []byte)
+			0x68, 0x65, 0x6c, 0x6c, 0x6f,
+		},
+	},
+	{
+		Name:  "String(empty)",
+		Value: "",
+		Binary: []byte{
+			// String Marker
+			0x06,
+			// U29S(length 0, inline)
+			0x01,
+		},
+	},
+	{
+		Name:  "XMLDocument",
+		Value: amf0.XMLDocument("<a/>"),
+		Binary: []byte{
+			// XMLDocument Marker
+			0x07,
+			// U29X(length 4, inline)
+			0x09,
+			// Value(<a/>: []byte)
+			0x3c, 0x61, 0x2f, 0x3e,
+		},
+	},
+	{
+		Name:  "Date",
+		Value: time.UnixMilli(1000).In(time.UTC),
+		Binary: []byte{
+			// Date Marker
+			0x08,
+			// U29D(inline)
+			0x01,
+			// Value(1000: double) BigEndian
+			0x40, 0x8f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
+	{
+		Name:  "Array(dense)",
+		Value: []interface{}{1, "a"},
+		Binary: []byte{
+			// Array Marker
+			0x09,
+			// U29A(length 2, inline)
+			0x05,
+			// - End of associative parts
+			0x01,
+			// - Integer(1)
+			0x04, 0x01,
+			// - String(a)
+			0x06, 0x03, 0x61,
+		},
+	},
+	{
+		Name: "Array(associative)",
+		Value: Array{
+			Assoc: map[string]interface{}{
+				"k": 1,
+			},
+			Dense: []interface{}{true},
+		},
+		Binary: []byte{
+			// Array Marker
+			0x09,
+			// U29A(length 1, inline)
+			0x03,
+			// - Key(k)
+			0x03, 0x6b,
+			// - Integer(1)
+			0x04, 0x01,
+			// - End of associative parts
+			0x01,
+			// - True
+			0x03,
+		},
+	},
+	{
+		Name: "Object(anonymous)",
+		Value: map[string]interface{}{
+			"a": 1,
+			"b": "x",
+		},
+		Binary: []byte{
+			// Object Marker
+			0x0a,
+			// U29O-traits(0 sealed members, dynamic, inline)
+			0x0b,
+			// - Class name(empty)
+			0x01,
+			// - Key(a)
+			0x03, 0x61,
+			// - Integer(1)
+			0x04, 0x01,
+			// - Key(b)
+			0x03, 0x62,
+			// - String(x)
+			0x06, 0x03, 0x78,
+			// - End of dynamic members
+			0x01,
+		},
+	},
+	{
+		Name: "Object(unregistered class)",
+		Value: TypedObject{
+			ClassName: "Foo",
+			Fields: map[string]interface{}{
+				"a": 1,
+			},
+		},
+		Binary: []byte{
+			// Object Marker
+			0x0a,
+			// U29O-traits(0 sealed members, dynamic, inline)
+			0x0b,
+			// - Class name(Foo)
+			0x07, 0x46, 0x6f, 0x6f,
+			// - Key(a)
+			0x03, 0x61,
+			// - Integer(1)
+			0x04, 0x01,
+			// - End of dynamic members
+			0x01,
+		},
+	},
+	{
+		Name:  "Object(registered class)",
+		Value: samplePoint{X: 1, Y: 2},
+		Binary: []byte{
+			// Object Marker
+			0x0a,
+			// U29O-traits(2 sealed members, inline)
+			0x23,
+			// - Class name(Point)
+			0x0b, 0x50, 0x6f, 0x69, 0x6e, 0x74,
+			// - Member names(x, y)
+			0x03, 0x78, 0x03, 0x79,
+			// - Integer(1), Integer(2)
+			0x04, 0x01, 0x04, 0x02,
+		},
+	},
+	{
+		Name:  "XML",
+		Value: XML("<a/>"),
+		Binary: []byte{
+			// XML Marker
+			0x0b,
+			// U29X(length 4, inline)
+			0x09,
+			// Value(<a/>: []byte)
+			0x3c, 0x61, 0x2f, 0x3e,
+		},
+	},
+	{
+		Name:  "ByteArray",
+		Value: []byte{0x01, 0x02, 0x03},
+		Binary: []byte{
+			// ByteArray Marker
+			0x0c,
+			// U29B(length 3, inline)
+			0x07,
+			// Value
+			0x01, 0x02, 0x03,
+		},
+	},
+	{
+		Name:  "Vector.<int>",
+		Value: VectorInt{1, -1},
+		Binary: []byte{
+			// Vector.<int> Marker
+			0x0d,
+			// U29V(length 2, inline)
+			0x05,
+			// - Not fixed
+			0x00,
+			// - Items
+			0x00, 0x00, 0x00, 0x01,
+			0xff, 0xff, 0xff, 0xff,
+		},
+	},
+	{
+		Name:  "Vector.<uint>",
+		Value: VectorUint{1},
+		Binary: []byte{
+			// Vector.<uint> Marker
+			0x0e,
+			// U29V(length 1, inline)
+			0x03,
+			// - Not fixed
+			0x00,
+			// - Items
+			0x00, 0x00, 0x00, 0x01,
+		},
+	},
+	{
+		Name:  "Vector.<Number>",
+		Value: VectorDouble{1.5},
+		Binary: []byte{
+			// Vector.<Number> Marker
+			0x0f,
+			// U29V(length 1, inline)
+			0x03,
+			// - Not fixed
+			0x00,
+			// - Items
+			0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		},
+	},
+	{
+		Name: "Vector.<Object>",
+		Value: VectorObject{
+			TypeName: "*",
+			Fixed:    true,
+			Items:    []interface{}{"a"},
+		},
+		Binary: []byte{
+			// Vector.<Object> Marker
+			0x10,
+			// U29V(length 1, inline)
+			0x03,
+			// - Fixed
+			0x01,
+			// - Type name(*)
+			0x03, 0x2a,
+			// - String(a)
+			0x06, 0x03, 0x61,
+		},
+	},
+	{
+		Name: "Dictionary",
+		Value: Dictionary{
+			Entries: []DictionaryEntry{
+				{Key: 1, Value: "a"},
+			},
+		},
+		Binary: []byte{
+			// Dictionary Marker
+			0x11,
+			// U29Dict(length 1, inline)
+			0x03,
+			// - Not weak keys
+			0x00,
+			// - Integer(1)
+			0x04, 0x01,
+			// - String(a)
+			0x06, 0x03, 0x61,
+		},
+	},
+	{
+		Name: "ArrayCollection",
+		Value: &ArrayCollection{
+			Source: []interface{}{1},
+		},
+		Binary: []byte{
+			// Object Marker
+			0x0a,
+			// U29O-traits-ext(inline)
+			0x07,
+			// - Class name(flex.messaging.io.ArrayCollection)
+			0x43,
+			0x66, 0x6c, 0x65, 0x78, 0x2e, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x69, 0x6e, 0x67, 0x2e,
+			0x69, 0x6f, 0x2e,
+			0x41, 0x72, 0x72, 0x61, 0x79, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+			// - Array(length 1)
+			0x09, 0x03, 0x01,
+			// - Integer(1)
+			0x04, 0x01,
+		},
+	},
+	{
+		Name:  "String reference",
+		Value: []interface{}{"a", "a"},
+		Binary: []byte{
+			// Array Marker
+			0x09,
+			// U29A(length 2, inline)
+			0x05,
+			// - End of associative parts
+			0x01,
+			// - String(a)
+			0x06, 0x03, 0x61,
+			// - String(reference 0)
+			0x06, 0x00,
+		},
+	},
+	{
+		Name:  "Traits reference",
+		Value: []interface{}{samplePoint{X: 1, Y: 2}, samplePoint{X: 3, Y: 4}},
+		Binary: []byte{
+			// Array Marker
+			0x09,
+			// U29A(length 2, inline)
+			0x05,
+			// - End of associative parts
+			0x01,
+			// - Object Marker
+			0x0a,
+			//   U29O-traits(2 sealed members, inline)
+			0x23,
+			//   - Class name(Point)
+			0x0b, 0x50, 0x6f, 0x69, 0x6e, 0x74,
+			//   - Member names(x, y)
+			0x03, 0x78, 0x03, 0x79,
+			//   - Integer(1), Integer(2)
+			0x04, 0x01, 0x04, 0x02,
+			// - Object Marker
+			0x0a,
+			//   U29O-traits-ref(0)
+			0x01,
+			//   - Integer(3), Integer(4)
+			0x04, 0x03, 0x04, 0x04,
+		},
+	},
+}
+
+// sharedObjectBinary An array which has the same object twice. The second one is a reference to the object.
+var sharedObjectBinary = []byte{
+	// Array Marker
+	0x09,
+	// U29A(length 2, inline)
+	0x05,
+	// - End of associative parts
+	0x01,
+	// - Object Marker
+	0x0a,
+	//   U29O-traits(0 sealed members, dynamic, inline)
+	0x0b,
+	//   - Class name(empty)
+	0x01,
+	//   - Key(a)
+	0x03, 0x61,
+	//   - Integer(1)
+	0x04, 0x01,
+	//   - End of dynamic members
+	0x01,
+	// - Object Marker
+	0x0a,
+	//   U29O-ref(1)
+	0x02,
+}
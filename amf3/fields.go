@@ -0,0 +1,22 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf3
+
+import (
+	"reflect"
+
+	"github.com/yutopp/go-amf0/internal/structfields"
+)
+
+// tagNames Names of struct tags which are used by this package. `amf0` tags are used if fields do not have `amf3` tags.
+var tagNames = []string{"amf3", "amf0"}
+
+// cachedTypeFields Returns fields of the struct type resolved by tagNames. Results are cached per type.
+func cachedTypeFields(ty reflect.Type) *structfields.Fields {
+	return structfields.Cached(ty, tagNames...)
+}
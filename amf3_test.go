@@ -0,0 +1,249 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// sampleAMF3Codec An AMF3 codec which supports only inline strings
+type sampleAMF3Codec struct{}
+
+func (c *sampleAMF3Codec) DecodeAMF3(r io.Reader, v interface{}) error {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if header[0] != 0x06 || header[1]&0x81 != 0x01 {
+		return fmt.Errorf("not an inline short string")
+	}
+
+	str := make([]byte, header[1]>>1)
+	if _, err := io.ReadFull(r, str); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case *string:
+		*v = string(str)
+	case *interface{}:
+		*v = string(str)
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+
+	return nil
+}
+
+func (c *sampleAMF3Codec) EncodeAMF3(w io.Writer, v interface{}) error {
+	str, ok := v.(string)
+	if !ok || len(str) > 63 {
+		return fmt.Errorf("unsupported value: %+v", v)
+	}
+
+	_, err := w.Write(append([]byte{0x06, byte(len(str)<<1 | 0x01)}, str...))
+	return err
+}
+
+var amf3StringBinary = []byte{
+	// AVMPlusObject Marker
+	0x11,
+	// - AMF3 String Marker
+	0x06,
+	//   U29S(length 2, inline)
+	0x05,
+	//   Value(hi: []byte)
+	0x68, 0x69,
+}
+
+func TestDecodeAVMPlusObject(t *testing.T) {
+	t.Run("field", func(t *testing.T) {
+		bin := []byte{
+			0x03,             // Object Marker
+			0x00, 0x01, 0x61, // Key(a)
+		}
+		bin = append(bin, amf3StringBinary...)
+		bin = append(bin, 0x00, 0x00, 0x09) // End
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v struct {
+			A AVMPlusObject `amf0:"a"`
+		}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, AVMPlusObject(amf3StringBinary[1:]), v.A)
+		require.Equal(t, 0, r.Len())
+	})
+
+	t.Run("not interface{}", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(amf3StringBinary))
+
+		var v string
+		err := dec.Decode(&v)
+		require.IsType(t, &NotAssignableError{}, err)
+	})
+
+	t.Run("not AVMPlusObject marker", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{0x05})) // Null
+
+		var v AVMPlusObject
+		err := dec.Decode(&v)
+		require.IsType(t, &UnexpectedMarkerError{}, err)
+	})
+
+	t.Run("U29 of 4 bytes", func(t *testing.T) {
+		bin := []byte{
+			0x11,                         // AVMPlusObject Marker
+			0x04, 0xff, 0xff, 0xff, 0xff, // AMF3 Integer Marker, U29(max)
+		}
+		dec := NewDecoder(bytes.NewReader(bin))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, AVMPlusObject(bin[1:]), v)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		bin := avmPlusObjectTest.Binary[:len(avmPlusObjectTest.Binary)-7] // A part of the AMF3 payload is lost
+		dec := NewDecoder(bytes.NewReader(bin))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			bin  []byte
+		}{
+			{
+				name: "traits reference out of range",
+				bin:  []byte{0x11, 0x0a, 0x05}, // AMF3 Object Marker, U29O-traits-ref(1)
+			},
+			{
+				name: "externalizable",
+				bin:  []byte{0x11, 0x0a, 0x07, 0x03, 0x61}, // AMF3 Object Marker, U29O-traits-ext, Class name(a)
+			},
+			{
+				name: "unknown marker",
+				bin:  []byte{0x11, 0x20},
+			},
+		} {
+			tc := tc // capture
+
+			t.Run(tc.name, func(t *testing.T) {
+				dec := NewDecoder(bytes.NewReader(tc.bin))
+
+				var v interface{}
+				err := dec.Decode(&v)
+				require.IsType(t, &DecodeError{}, err)
+			})
+		}
+	})
+}
+
+func TestDecodeAMF3Codec(t *testing.T) {
+	t.Run("interface{}", func(t *testing.T) {
+		r := bytes.NewReader(amf3StringBinary)
+		dec := NewDecoder(r, WithDecodeAMF3(&sampleAMF3Codec{}))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, "hi", v)
+		require.Equal(t, 0, r.Len())
+	})
+
+	t.Run("string", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(amf3StringBinary), WithDecodeAMF3(&sampleAMF3Codec{}))
+
+		var v string
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, "hi", v)
+	})
+
+	t.Run("AVMPlusObject is captured", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(amf3StringBinary), WithDecodeAMF3(&sampleAMF3Codec{}))
+
+		var v AVMPlusObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, AVMPlusObject(amf3StringBinary[1:]), v)
+	})
+}
+
+func TestEncodeAVMPlusObject(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(AVMPlusObject(nil))
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x05}, buf.Bytes())
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithValidateRawMessage())
+
+		err := enc.Encode(AVMPlusObject(avmPlusPayload))
+		require.Nil(t, err)
+
+		err = enc.Encode(AVMPlusObject(avmPlusPayload[:len(avmPlusPayload)-1]))
+		require.IsType(t, &DecodeError{}, err)
+
+		err = enc.Encode(AVMPlusObject(append(append([]byte{}, avmPlusPayload...), 0x01)))
+		require.IsType(t, &DecodeError{}, err)
+	})
+}
+
+func TestEncodeAMF3Value(t *testing.T) {
+	t.Run("codec", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithEncodeAMF3(&sampleAMF3Codec{}))
+
+		err := enc.Encode(AMF3Value{Value: "hi"})
+		require.Nil(t, err)
+		require.Equal(t, amf3StringBinary, buf.Bytes())
+	})
+
+	t.Run("no codec", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(AMF3Value{Value: "hi"})
+		require.Equal(t, ErrNoAMF3Codec, err)
+		require.Equal(t, 0, buf.Len())
+	})
+}
+
+func TestValueAVMPlusObject(t *testing.T) {
+	var v Value
+	err := NewDecoder(bytes.NewReader(avmPlusObjectTest.Binary)).Decode(&v)
+	require.Nil(t, err)
+
+	elem, ok := v.Get("1")
+	require.True(t, ok)
+	require.Equal(t, MarkerAVMPlusObject, elem.Kind)
+	require.Equal(t, AVMPlusObject(avmPlusPayload), elem.AMF3)
+
+	buf := bytes.NewBuffer([]byte{})
+	err = NewEncoder(buf).Encode(&v)
+	require.Nil(t, err)
+	require.Equal(t, avmPlusObjectTest.Binary, buf.Bytes())
+}
@@ -8,40 +8,182 @@
 package amf0
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"math"
 	"reflect"
 	"time"
 	"unicode/utf8"
+
+	"github.com/yutopp/go-amf0/internal/shared"
 )
 
 // Decoder Read from the reader and decode them into objects in Golang
 type Decoder struct {
 	r io.Reader
+
+	refs shared.References
+
+	peekedU8  uint8
+	hasPeeked bool
+
+	capture *bytes.Buffer // bytes which are read are also written to it if not nil
+
+	frames []tokenFrame // a stack of objects and arrays opened by Token
+	depth  int          // a nesting level of Decode calls
+
+	limiter shared.Limiter // counts a nesting level and bytes which are read since the decoder is created or reset
+
+	useTimeZone       bool
+	useOrderedObjects bool
+	amf3              AMF3Codec
+}
+
+// Unmarshaler The interface implemented by types which can decode AMF0 into themselves
+// UnmarshalAMF0 must read exactly one value by methods of the decoder, such as Decode and Read.
+type Unmarshaler interface {
+	UnmarshalAMF0(dec *Decoder) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// DecoderOption An option for Decoder
+type DecoderOption func(*Decoder)
+
+// WithDecodeTimeZone Apply time zone fields of Dates to decoded time.Time values as fixed zones.
+// The time zone field is interpreted as an offset in minutes east of UTC. Otherwise, time.Time values are in UTC.
+func WithDecodeTimeZone() DecoderOption {
+	return func(dec *Decoder) {
+		dec.useTimeZone = true
+	}
+}
+
+// WithOrderedObjects Decode Objects and ECMA Arrays into OrderedObject and OrderedECMAArray instead of maps when targets are interface{}.
+func WithOrderedObjects() DecoderOption {
+	return func(dec *Decoder) {
+		dec.useOrderedObjects = true
+	}
+}
+
+// WithDecodeAMF3 Decode values after MarkerAVMPlusObject by the AMF3 codec.
+// Otherwise, they are decoded into AVMPlusObject when targets are interface{}.
+func WithDecodeAMF3(c AMF3Codec) DecoderOption {
+	return func(dec *Decoder) {
+		dec.amf3 = c
+	}
+}
+
+// DecoderOptions Limits of resources which are consumed to decode values from untrusted inputs.
+// Values which exceed limits fail with LimitExceededError before they are allocated. Zero values mean unlimited.
+type DecoderOptions struct {
+	// MaxDepth The maximum nesting level of values. Top-level values are at level 1, and their elements and properties are at level 2.
+	MaxDepth int
+	// MaxArrayLength The maximum number of elements of Strict Arrays, and Arrays, Vectors and Dictionaries of AMF3 values
+	MaxArrayLength int
+	// MaxStringLength The maximum number of bytes of strings, including keys, class names and XML Documents, and ByteArrays of AMF3 values
+	MaxStringLength int
+	// MaxBytes The maximum number of bytes which are read since the decoder is created or reset
+	MaxBytes int64
+	// MaxObjectKeys The maximum number of properties of each Object, ECMA Array and Typed Object
+	MaxObjectKeys int
+}
+
+// WithDecoderOptions Limit resources which are consumed by the decoder
+func WithDecoderOptions(o DecoderOptions) DecoderOption {
+	return func(dec *Decoder) {
+		dec.limiter.Options = shared.DecoderOptions(o)
+	}
 }
 
 // NewDecoder Create a new instance of Decoder
-func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	dec := &Decoder{
 		r: r,
 	}
+	for _, opt := range opts {
+		opt(dec)
+	}
+
+	return dec
 }
 
 // Decode Decode objects
 func (dec *Decoder) Decode(v interface{}) error {
+	if err := dec.beginValue(); err != nil {
+		return err
+	}
+
+	dec.depth++
+	defer func() { dec.depth-- }()
+
 	rv := reflect.ValueOf(v)
 	return dec.decode(rv)
 }
 
+// Read Read raw bytes from the reader. It is intended to be used by Unmarshaler implementations to read raw AMF0 values.
+func (dec *Decoder) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	p, err := dec.limiter.LimitRead(p)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if dec.hasPeeked {
+		p[0] = dec.peekedU8
+		dec.hasPeeked = false
+		n = 1
+	} else {
+		n, err = dec.r.Read(p)
+	}
+	dec.limiter.NumBytes += int64(n)
+
+	if dec.capture != nil {
+		dec.capture.Write(p[:n])
+	}
+
+	return n, err
+}
+
+// Limits Returns limits of resources which remain for the value being decoded.
+// It is intended to be used by AMF3 codecs to apply DecoderOptions to AMF3 values. MaxDepth and MaxBytes are reduced by the current level and bytes which are already read.
+func (dec *Decoder) Limits() DecoderOptions {
+	level := dec.limiter.Nesting + len(dec.frames)
+	if dec.limiter.Nesting == 0 {
+		level++ // The next value
+	}
+
+	return DecoderOptions(dec.limiter.Remaining(level))
+}
+
 // Reset Reset a state of the decoder
+// References are resolved within values decoded between resets, thus Reset should be called for each message
 func (dec *Decoder) Reset(r io.Reader) {
 	dec.r = r
+	dec.refs = nil
+	dec.hasPeeked = false
+	dec.frames = nil
+	dec.limiter.NumBytes = 0
 }
 
 func (dec *Decoder) decode(rv reflect.Value) error {
+	if err := dec.enterNest(); err != nil {
+		return err
+	}
+	defer dec.leaveNest()
+
+	if u, ok := dec.unmarshalerOf(rv); ok {
+		return u.UnmarshalAMF0(dec)
+	}
+
 	marker, err := dec.readU8()
 	if err != nil {
 		return err
@@ -99,6 +241,9 @@ func (dec *Decoder) decode(rv reflect.Value) error {
 	case MarkerTypedObject:
 		return dec.decodeTypedObject(rv)
 
+	case MarkerAVMPlusObject:
+		return dec.decodeAVMPlusObject(rv)
+
 	default:
 		return &UnexpectedMarkerError{
 			Marker: marker,
@@ -106,67 +251,54 @@ func (dec *Decoder) decode(rv reflect.Value) error {
 	}
 }
 
-func (dec *Decoder) decodeNumber(rv reflect.Value) error {
-	num, err := dec.readDouble()
-	if err != nil {
-		return wrapEOF(err)
+// unmarshalerOf Returns Unmarshaler if the pointer or the nested pointer implements it. Nil pointers are allocated on the way.
+// If the Unmarshaler is in nested pointers and the next value is Null or Undefined, the pointer will be set to nil instead.
+func (dec *Decoder) unmarshalerOf(rv reflect.Value) (Unmarshaler, bool) {
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, false
 	}
 
-	rv, err = indirect(rv)
-	if err != nil {
-		return err
+	depth := 0
+	for ty := rv.Type(); !ty.Implements(unmarshalerType); ty = ty.Elem() {
+		if ty.Elem().Kind() != reflect.Ptr {
+			return nil, false
+		}
+		depth++
 	}
 
-	switch rv.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		fallthrough
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		fallthrough
-	case reflect.Float32, reflect.Float64:
-		fallthrough
-	case reflect.Interface:
-		rv.Set(reflect.ValueOf(num).Convert(rv.Type()))
+	if depth > 0 {
+		marker, err := dec.peekU8()
+		if err != nil || Marker(marker) == MarkerNull || Marker(marker) == MarkerUndefined {
+			return nil, false
+		}
+	}
 
-	default:
-		return &NotAssignableError{
-			Message: "Not numeric type",
-			Kind:    rv.Kind(),
-			Type:    rv.Type(),
+	for i := 0; i < depth; i++ {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Type().Elem().Elem()))
 		}
+		rv = rv.Elem()
 	}
 
-	return nil
+	return rv.Interface().(Unmarshaler), true
 }
 
-func (dec *Decoder) decodeBoolean(rv reflect.Value) error {
-	num, err := dec.readU8()
+func (dec *Decoder) decodeNumber(rv reflect.Value) error {
+	num, err := dec.readDouble()
 	if err != nil {
 		return wrapEOF(err)
 	}
 
-	tf := false
-	if num != 0 {
-		tf = true
-	}
+	return shared.SetNumber(rv, reflect.ValueOf(num))
+}
 
-	rv, err = indirect(rv)
+func (dec *Decoder) decodeBoolean(rv reflect.Value) error {
+	num, err := dec.readU8()
 	if err != nil {
-		return err
-	}
-
-	switch rv.Kind() {
-	case reflect.Bool, reflect.Interface:
-		rv.Set(reflect.ValueOf(tf))
-
-	default:
-		return &NotAssignableError{
-			Message: "Not boolean type",
-			Kind:    rv.Kind(),
-			Type:    rv.Type(),
-		}
+		return wrapEOF(err)
 	}
 
-	return nil
+	return shared.SetBool(rv, num != 0)
 }
 
 func (dec *Decoder) decodeString(rv reflect.Value) error {
@@ -175,46 +307,52 @@ func (dec *Decoder) decodeString(rv reflect.Value) error {
 		return wrapEOF(err)
 	}
 
-	rv, err = indirect(rv)
+	rv, err = shared.Indirect(rv)
 	if err != nil {
 		return err
 	}
 
-	switch rv.Kind() {
-	case reflect.String, reflect.Interface:
-		rv.Set(reflect.ValueOf(str))
-
-	default:
-		return &NotAssignableError{
-			Message: "Not string type",
-			Kind:    rv.Kind(),
-			Type:    rv.Type(),
-		}
-	}
-
-	return nil
+	return shared.SetString(rv, str)
 }
 
 func (dec *Decoder) decodeObject(rv reflect.Value) error {
-	rv, err := indirect(rv)
+	rv, err := shared.Indirect(rv)
 	if err != nil {
 		return err
 	}
 
+	if ty, ok := dec.orderedTypeOf(rv, reflect.TypeOf(OrderedObject{})); ok {
+		return dec.decodeOrderedProperties(rv, ty)
+	}
+
 	switch rv.Kind() {
 	case reflect.Interface:
-		if rv.IsNil() {
-			rv.Set(reflect.MakeMap(reflect.TypeOf(map[string]interface{}{})))
-			rv = rv.Elem()
-		}
+		rv.Set(reflect.MakeMap(reflect.TypeOf(map[string]interface{}{})))
+		rv = rv.Elem()
 
 	case reflect.Map:
 		if rv.IsNil() {
 			rv.Set(reflect.MakeMap(rv.Type()))
 		}
+
+	case reflect.Struct:
+		// Do nothing
+
+	default:
+		return &NotAssignableError{
+			Message: "Not map or struct or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
 	}
 
-	for {
+	dec.refs.Add(rv)
+
+	return dec.decodeObjectProperties(rv)
+}
+
+func (dec *Decoder) decodeObjectProperties(rv reflect.Value) error {
+	for numKeys := 1; ; numKeys++ {
 		key, err := dec.readUTF8()
 		if err != nil {
 			return wrapEOF(err)
@@ -233,44 +371,47 @@ func (dec *Decoder) decodeObject(rv reflect.Value) error {
 			break
 		}
 
+		if err := dec.limiter.CheckObjectKeys(numKeys); err != nil {
+			return err
+		}
+
 		switch rv.Kind() {
 		case reflect.Map:
+			k, err := shared.MapKey(rv.Type().Key(), key)
+			if err != nil {
+				return err
+			}
+
 			v := reflect.New(rv.Type().Elem())
 			if err := dec.decode(v); err != nil {
 				return err
 			}
 
-			rv.SetMapIndex(reflect.ValueOf(key), v.Elem())
+			rv.SetMapIndex(k, v.Elem())
 
 		case reflect.Struct:
-			var v reflect.Value
-			found := false
-
-			for i := 0; i < rv.NumField(); i++ {
-				fieldTy := rv.Type().Field(i)
-				if fieldTy.Name == key {
-					goto foundField
-				}
-
-				if name, ok := fieldTy.Tag.Lookup("amf0"); !ok {
-					continue
-				} else {
-					if name != key {
-						continue
-					}
+			f, ok := cachedTypeFields(rv.Type()).ByName[key]
+			if !ok {
+				// discard
+				var null interface{}
+				if err := dec.decode(reflect.ValueOf(&null)); err != nil {
+					return err
 				}
+				continue
+			}
 
-			foundField:
-				v = rv.Field(i).Addr()
-				found = true
+			v, err := fieldByIndexAlloc(rv, f.Index)
+			if err != nil {
+				return err
 			}
-			if !found {
-				// discard
-				var null interface{}
-				v = reflect.ValueOf(&null)
+			if f.AsString {
+				if err := dec.decodeStringified(v); err != nil {
+					return err
+				}
+				continue
 			}
 
-			if err := dec.decode(v); err != nil {
+			if err := dec.decode(v.Addr()); err != nil {
 				return err
 			}
 		}
@@ -279,7 +420,18 @@ func (dec *Decoder) decodeObject(rv reflect.Value) error {
 	return nil
 }
 
-func (dec *Decoder) decodeObjectProperty(rk *string, rv reflect.Value) (bool, error) {
+// decodeStringified Decodes a String into a boolean or numeric value
+func (dec *Decoder) decodeStringified(rv reflect.Value) error {
+	var s string
+	if err := dec.decode(reflect.ValueOf(&s)); err != nil {
+		return err
+	}
+
+	return shared.SetStringified(rv, s)
+}
+
+// decodeObjectProperty Decodes a pair of a key and a value, or the end of the object. numKeys is the number of properties including the pair.
+func (dec *Decoder) decodeObjectProperty(numKeys int, rk *string, rv reflect.Value) (bool, error) {
 	key, err := dec.readUTF8()
 	if err != nil {
 		return false, wrapEOF(err)
@@ -299,6 +451,10 @@ func (dec *Decoder) decodeObjectProperty(rk *string, rv reflect.Value) (bool, er
 		return true, nil
 	}
 
+	if err := dec.limiter.CheckObjectKeys(numKeys); err != nil {
+		return false, err
+	}
+
 	*rk = key
 	return false, dec.decode(rv)
 }
@@ -308,38 +464,35 @@ func (dec *Decoder) decodeMovieClip(rv reflect.Value) error {
 }
 
 func (dec *Decoder) decodeNull(rv reflect.Value) error {
-	rv, err := indirect(rv)
-	if err != nil {
-		return err
-	}
-
-	if rv.Kind() != reflect.Map && rv.Kind() != reflect.Slice && rv.Kind() != reflect.Interface {
-		return &NotAssignableError{
-			Message: "Not reference type",
-			Kind:    rv.Kind(),
-			Type:    rv.Type(),
-		}
-	}
-
-	rv.Set(reflect.Zero(rv.Type()))
-
-	return nil
+	return shared.SetNothing(rv, nil)
 }
 
 func (dec *Decoder) decodeUndefined(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: Undefined")
+	return shared.SetNothing(rv, Undefined)
 }
 
 func (dec *Decoder) decodeReference(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: Reference")
+	index, err := dec.readU16()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	return dec.refs.Assign(int(index), rv)
 }
 
 func (dec *Decoder) decodeECMAArray(rv reflect.Value) error {
-	rv, err := indirect(rv)
+	rv, err := shared.Indirect(rv)
 	if err != nil {
 		return err
 	}
 
+	if ty, ok := dec.orderedTypeOf(rv, reflect.TypeOf(OrderedECMAArray{})); ok {
+		if _, err := dec.readU32(); err != nil {
+			return wrapEOF(err)
+		}
+		return dec.decodeOrderedProperties(rv, ty)
+	}
+
 	if rv.Kind() != reflect.Interface && rv.Kind() != reflect.Map {
 		return &NotAssignableError{
 			Message: "Not map or interface type",
@@ -348,25 +501,18 @@ func (dec *Decoder) decodeECMAArray(rv reflect.Value) error {
 		}
 	}
 
-	if rv.IsNil() {
-		switch rv.Kind() {
-		case reflect.Interface:
-			rv.Set(reflect.MakeMap(reflect.TypeOf(ECMAArray{})))
-			rv = rv.Elem()
-		case reflect.Map:
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(reflect.MakeMap(reflect.TypeOf(ECMAArray{})))
+		rv = rv.Elem()
+	case reflect.Map:
+		if rv.IsNil() {
 			rv.Set(reflect.MakeMap(rv.Type()))
 		}
 	}
 
-	if rv.Kind() == reflect.Map {
-		keyTy := rv.Type().Key()
-		if keyTy.Kind() != reflect.String {
-			return &NotAssignableError{
-				Message: "Key of map is not string type",
-				Kind:    keyTy.Kind(),
-				Type:    keyTy,
-			}
-		}
+	if err := shared.CheckMapKeyType(rv.Type().Key()); err != nil {
+		return err
 	}
 
 	numElems, err := dec.readU32()
@@ -375,11 +521,53 @@ func (dec *Decoder) decodeECMAArray(rv reflect.Value) error {
 	}
 	_ = numElems
 
+	dec.refs.Add(rv)
+
 	var key string
-	value := reflect.New(rv.Type().Elem())
+	for numKeys := 1; ; numKeys++ {
+		value := reflect.New(rv.Type().Elem())
+		isEnd, err := dec.decodeObjectProperty(numKeys, &key, value)
+		if err != nil {
+			return err
+		}
+		if isEnd {
+			break
+		}
+
+		k, err := shared.MapKey(rv.Type().Key(), key)
+		if err != nil {
+			return err
+		}
+		rv.SetMapIndex(k, value.Elem())
+	}
+
+	return nil
+}
+
+// orderedTypeOf Returns a type of ordered objects if the value should be decoded as them
+func (dec *Decoder) orderedTypeOf(rv reflect.Value, defaultTy reflect.Type) (reflect.Type, bool) {
+	switch rv.Type() {
+	case reflect.TypeOf(OrderedObject{}), reflect.TypeOf(OrderedECMAArray{}):
+		return rv.Type(), true
+	}
+
+	if dec.useOrderedObjects && rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		return defaultTy, true
+	}
+
+	return nil, false
+}
+
+// decodeOrderedProperties Decodes properties into OrderedObject or OrderedECMAArray.
+// The slice grows while decoding, thus it is registered as a reference after all properties are decoded.
+func (dec *Decoder) decodeOrderedProperties(rv reflect.Value, ty reflect.Type) error {
+	index := dec.refs.Reserve()
 
-	for {
-		isEnd, err := dec.decodeObjectProperty(&key, value)
+	kvs := []KeyValue{}
+	var key string
+	for numKeys := 1; ; numKeys++ {
+		var value interface{}
+		isEnd, err := dec.decodeObjectProperty(numKeys, &key, reflect.ValueOf(&value))
 		if err != nil {
 			return err
 		}
@@ -387,20 +575,38 @@ func (dec *Decoder) decodeECMAArray(rv reflect.Value) error {
 			break
 		}
 
-		rv.SetMapIndex(reflect.ValueOf(key), value.Elem())
+		kvs = append(kvs, KeyValue{
+			Key:   key,
+			Value: value,
+		})
 	}
 
+	v := reflect.ValueOf(kvs).Convert(ty)
+	rv.Set(v)
+	dec.refs[index] = v
+
 	return nil
 }
 
 // skip ObjectEnd
 
 func (dec *Decoder) decodeStrictArray(rv reflect.Value) error {
-	rv, err := indirect(rv)
+	rv, err := shared.Indirect(rv)
 	if err != nil {
 		return err
 	}
 
+	if rv.Kind() != reflect.Interface {
+		if u, ok := rv.Addr().Interface().(encoding.BinaryUnmarshaler); ok {
+			// Decoded in the same way as []byte
+			var data []byte
+			if err := dec.decodeStrictArray(reflect.ValueOf(&data)); err != nil {
+				return err
+			}
+			return u.UnmarshalBinary(data)
+		}
+	}
+
 	if rv.Kind() != reflect.Interface && rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
 		return &NotAssignableError{
 			Message: "Not array or slice or interface type",
@@ -413,21 +619,25 @@ func (dec *Decoder) decodeStrictArray(rv reflect.Value) error {
 	if err != nil {
 		return wrapEOF(err)
 	}
+	if err := dec.limiter.CheckArrayLength(int64(length)); err != nil {
+		return err
+	}
+	if err := dec.limiter.CheckRemainingBytes(int64(length)); err != nil { // Each element has one byte at least
+		return err
+	}
 	if length > math.MaxInt32 {
 		// The specification said "maximum 4294967295", however we cannot support that...
 		// TODO: Support if possible
 		return fmt.Errorf("unsupported array length: Expected <= %d, Actual = %d", math.MaxInt32, length)
 	}
 
-	if rv.Kind() == reflect.Interface || rv.Kind() == reflect.Slice {
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(make([]interface{}, int(length))))
+		rv = rv.Elem()
+	case reflect.Slice:
 		if rv.IsNil() {
-			switch rv.Kind() {
-			case reflect.Interface:
-				rv.Set(reflect.ValueOf(make([]interface{}, int(length))))
-				rv = rv.Elem()
-			case reflect.Slice:
-				rv.Set(reflect.MakeSlice(rv.Type(), int(length), int(length)))
-			}
+			rv.Set(reflect.MakeSlice(rv.Type(), int(length), int(length)))
 		}
 	}
 
@@ -437,6 +647,8 @@ func (dec *Decoder) decodeStrictArray(rv reflect.Value) error {
 		}
 	}
 
+	dec.refs.Add(rv)
+
 	for i := 0; i < int(length); i++ {
 		if err := dec.decode(rv.Index(i).Addr()); err != nil {
 			return err
@@ -447,7 +659,7 @@ func (dec *Decoder) decodeStrictArray(rv reflect.Value) error {
 }
 
 func (dec *Decoder) decodeDate(rv reflect.Value) error {
-	rv, err := indirect(rv)
+	rv, err := shared.Indirect(rv)
 	if err != nil {
 		return err
 	}
@@ -460,9 +672,9 @@ func (dec *Decoder) decodeDate(rv reflect.Value) error {
 		}
 	}
 
-	if rv.Kind() == reflect.Struct && rv.Type() != reflect.TypeOf(time.Time{}) {
+	if rv.Kind() == reflect.Struct && rv.Type() != reflect.TypeOf(time.Time{}) && rv.Type() != reflect.TypeOf(Date{}) {
 		return &NotAssignableError{
-			Message: "Not time.Time type",
+			Message: "Not time.Time or Date type",
 			Kind:    rv.Kind(),
 			Type:    rv.Type(),
 		}
@@ -478,13 +690,22 @@ func (dec *Decoder) decodeDate(rv reflect.Value) error {
 		return wrapEOF(err)
 	}
 
-	t := time.Unix(int64(unixMs)/1000, int64(unixMs)%1000*int64(time.Nanosecond)).In(time.UTC)
+	if rv.Type() == reflect.TypeOf(Date{}) {
+		rv.Set(reflect.ValueOf(Date{
+			Millis: unixMs,
+			TZ:     tz,
+		}))
+		return nil
+	}
 
-	// Timezone is specified
-	// if tz != 0x00 {
-	// TODO: support
-	// }
-	_ = tz
+	t, err := shared.MillisToTime(unixMs)
+	if err != nil {
+		return err
+	}
+
+	if dec.useTimeZone {
+		t = t.In(time.FixedZone("", int(tz)*60))
+	}
 
 	rv.Set(reflect.ValueOf(t))
 
@@ -492,7 +713,17 @@ func (dec *Decoder) decodeDate(rv reflect.Value) error {
 }
 
 func (dec *Decoder) decodeLongString(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: LongString")
+	str, err := dec.readUTF8Long()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	return shared.SetString(rv, str)
 }
 
 // skip Unsupported
@@ -502,16 +733,181 @@ func (dec *Decoder) decodeRecordSet(rv reflect.Value) error {
 }
 
 func (dec *Decoder) decodeXMLDocument(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: XMLDocument")
+	str, err := dec.readUTF8Long()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	if rv.Kind() != reflect.Interface {
+		if _, ok := rv.Addr().Interface().(xml.Unmarshaler); ok {
+			return unmarshalXMLDocument(rv, str)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(XMLDocument(str)))
+
+	case reflect.String, reflect.Slice:
+		return shared.SetString(rv, str)
+
+	case reflect.Struct:
+		return unmarshalXMLDocument(rv, str)
+
+	default:
+		return &NotAssignableError{
+			Message: "Not string or byte slice or struct or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// unmarshalXMLDocument Unmarshals the document into the receiver value by encoding/xml
+func unmarshalXMLDocument(rv reflect.Value, str string) error {
+	if err := xml.Unmarshal([]byte(str), rv.Addr().Interface()); err != nil {
+		return &DecodeError{
+			Message: fmt.Sprintf("Failed to unmarshal XMLDocument: %+v", err),
+			Dump:    hex.Dump([]byte(str)),
+		}
+	}
+
+	return nil
 }
 
 func (dec *Decoder) decodeTypedObject(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: TypedObject")
+	className, err := dec.readUTF8()
+	if err != nil {
+		return wrapEOF(err)
+	}
+
+	rv, err = shared.Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		ty, ok := lookupRegisteredType(className)
+		if !ok {
+			to := TypedObject{}
+			toRv := reflect.ValueOf(&to).Elem()
+			if err := dec.decodeGenericTypedObject(className, toRv); err != nil {
+				return err
+			}
+			rv.Set(toRv)
+
+			return nil
+		}
+
+		isPtr := ty.Kind() == reflect.Ptr
+		if isPtr {
+			ty = ty.Elem()
+		}
+
+		v := reflect.New(ty)
+		dec.refs.Add(v.Elem())
+		if err := dec.decodeObjectProperties(v.Elem()); err != nil {
+			return err
+		}
+
+		if isPtr {
+			rv.Set(v)
+		} else {
+			rv.Set(v.Elem())
+		}
+
+	case reflect.Map:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+
+		dec.refs.Add(rv)
+		return dec.decodeObjectProperties(rv)
+
+	case reflect.Struct:
+		if rv.Type() == reflect.TypeOf(TypedObject{}) {
+			return dec.decodeGenericTypedObject(className, rv)
+		}
+
+		dec.refs.Add(rv)
+		return dec.decodeObjectProperties(rv)
+
+	default:
+		return &NotAssignableError{
+			Message: "Not map or struct or interface type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+func (dec *Decoder) decodeGenericTypedObject(className string, rv reflect.Value) error {
+	to := rv.Addr().Interface().(*TypedObject)
+	to.ClassName = className
+	to.Fields = make(map[string]interface{})
+	to.keys = nil
+
+	dec.refs.Add(rv)
+
+	// Fields are decoded into Value to re-encode the TypedObject as is
+	var key string
+	for numKeys := 1; ; numKeys++ {
+		value := &Value{}
+		isEnd, err := dec.decodeObjectProperty(numKeys, &key, reflect.ValueOf(value))
+		if err != nil {
+			return err
+		}
+		if isEnd {
+			break
+		}
+
+		if _, ok := to.Fields[key]; !ok {
+			to.keys = append(to.keys, key)
+		}
+		to.Fields[key] = value
+	}
+
+	return nil
+}
+
+// enterNest Increments the nesting level of values. leaveNest must be called when the value is decoded.
+// Objects and arrays opened by Token are also counted as levels.
+func (dec *Decoder) enterNest() error {
+	dec.limiter.Nesting++
+	return dec.limiter.CheckDepth(dec.limiter.Nesting + len(dec.frames))
+}
+
+func (dec *Decoder) leaveNest() {
+	dec.limiter.Nesting--
+}
+
+func (dec *Decoder) peekU8() (uint8, error) {
+	if !dec.hasPeeked {
+		// Read from the underlying reader directly not to capture the byte until it is consumed
+		u8 := make([]byte, 1)
+		if _, err := io.ReadFull(dec.r, u8); err != nil {
+			return 0, err
+		}
+		dec.peekedU8 = u8[0]
+		dec.hasPeeked = true
+	}
+
+	return dec.peekedU8, nil
 }
 
 func (dec *Decoder) readU8() (uint8, error) {
 	u8 := make([]byte, 1)
-	_, err := io.ReadAtLeast(dec.r, u8, 1)
+	_, err := io.ReadFull(dec, u8)
 	if err != nil {
 		return 0, err
 	}
@@ -521,7 +917,7 @@ func (dec *Decoder) readU8() (uint8, error) {
 
 func (dec *Decoder) readU16() (uint16, error) {
 	u16 := make([]byte, 2)
-	_, err := io.ReadAtLeast(dec.r, u16, 2)
+	_, err := io.ReadFull(dec, u16)
 	if err != nil {
 		return 0, err
 	}
@@ -540,7 +936,7 @@ func (dec *Decoder) readS16() (int16, error) {
 
 func (dec *Decoder) readU32() (uint32, error) {
 	bin := make([]byte, 4)
-	_, err := io.ReadAtLeast(dec.r, bin, len(bin))
+	_, err := io.ReadFull(dec, bin)
 	if err != nil {
 		return 0, err
 	}
@@ -550,7 +946,7 @@ func (dec *Decoder) readU32() (uint32, error) {
 
 func (dec *Decoder) readDouble() (float64, error) {
 	d := make([]byte, 8)
-	_, err := io.ReadAtLeast(dec.r, d, 8)
+	_, err := io.ReadFull(dec, d)
 	if err != nil {
 		return 0, err
 	}
@@ -560,8 +956,15 @@ func (dec *Decoder) readDouble() (float64, error) {
 }
 
 func (dec *Decoder) readUTF8Chars(len int) (string, error) {
+	if err := dec.limiter.CheckStringLength(int64(len)); err != nil {
+		return "", err
+	}
+	if err := dec.limiter.CheckRemainingBytes(int64(len)); err != nil {
+		return "", err
+	}
+
 	str := make([]byte, len) // TODO: optimize
-	_, err := io.ReadAtLeast(dec.r, str, len)
+	_, err := io.ReadFull(dec, str)
 	if err != nil {
 		return "", err
 	}
@@ -593,29 +996,30 @@ func (dec *Decoder) readUTF8() (string, error) {
 	return str, nil
 }
 
-func wrapEOF(err error) error {
-	if err == io.EOF {
-		return io.ErrUnexpectedEOF
+func (dec *Decoder) readUTF8Long() (string, error) {
+	len, err := dec.readU32()
+	if err != nil {
+		return "", err
+	}
+	if len == 0 {
+		return "", nil // empty
+	}
+	if uint64(len) > uint64(math.MaxInt32) {
+		return "", fmt.Errorf("unsupported string length: Expected <= %d, Actual = %d", math.MaxInt32, len)
 	}
 
-	return err
+	str, err := dec.readUTF8Chars(int(len))
+	if err != nil {
+		return "", err
+	}
+
+	return str, nil
 }
 
-func indirect(rv reflect.Value) (reflect.Value, error) {
-	if rv.Kind() != reflect.Ptr {
-		return reflect.Value{}, &NotAssignableError{
-			Message: "Not pointer",
-			Kind:    rv.Kind(),
-			Type:    rv.Type(),
-		}
-	}
-	if rv.IsNil() {
-		return reflect.Value{}, &NotAssignableError{
-			Message: "Nil",
-			Kind:    rv.Kind(),
-			Type:    rv.Type(),
-		}
+func wrapEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
 	}
 
-	return reflect.Indirect(rv), nil
+	return err
 }
@@ -9,13 +9,23 @@ package amf0
 
 import (
 	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
 func TestDecodeCommon(t *testing.T) {
-	for _, tc := range testCases {
+	allTestCases := append(append([]testCase{}, testCases...), typedObjectTest, unregisteredTypedObjectTest, avmPlusObjectTest)
+
+	for _, tc := range allTestCases {
 		tc := tc // capture
 
 		t.Run(tc.Name, func(t *testing.T) {
@@ -139,6 +149,16 @@ func TestDecodeNil(t *testing.T) {
 		require.Equal(t, []int(nil), v)
 	})
 
+	t.Run("assignable to pointer", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		v := intPtr(42)
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, (*int)(nil), v)
+	})
+
 	t.Run("NOT assignable to array (despite the length)", func(t *testing.T) {
 		r := bytes.NewReader(bin)
 		dec := NewDecoder(r)
@@ -358,3 +378,1096 @@ func TestDecodeUnknownMarker(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestDecodeLongString(t *testing.T) {
+	for _, size := range []int{65535, 65536} {
+		size := size // capture
+
+		t.Run(fmt.Sprintf("round-trip %d bytes", size), func(t *testing.T) {
+			str := strings.Repeat("a", size)
+
+			buf := bytes.NewBuffer([]byte{})
+			enc := NewEncoder(buf)
+			err := enc.Encode(str)
+			require.Nil(t, err)
+			bin := buf.Bytes()
+
+			t.Run("assignable to string", func(t *testing.T) {
+				r := bytes.NewReader(bin)
+				dec := NewDecoder(r)
+
+				var v string
+				err := dec.Decode(&v)
+				require.Nil(t, err)
+				require.Equal(t, str, v)
+				require.Equal(t, 0, r.Len())
+			})
+
+			t.Run("assignable to []byte", func(t *testing.T) {
+				r := bytes.NewReader(bin)
+				dec := NewDecoder(r)
+
+				var v []byte
+				err := dec.Decode(&v)
+				require.Nil(t, err)
+				require.Equal(t, []byte(str), v)
+			})
+
+			t.Run("assignable to interface{}", func(t *testing.T) {
+				r := bytes.NewReader(bin)
+				dec := NewDecoder(r)
+
+				var v interface{}
+				err := dec.Decode(&v)
+				require.Nil(t, err)
+				require.Equal(t, str, v)
+			})
+		})
+	}
+
+	t.Run("invalid utf8 sequence", func(t *testing.T) {
+		bin := []byte{0x0c, 0x00, 0x00, 0x00, 0x02, 0xc3, 0x28}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v string
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+
+	t.Run("NOT assignable to int", func(t *testing.T) {
+		bin := []byte{0x0c, 0x00, 0x00, 0x00, 0x01, 0x61}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v int
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeReference(t *testing.T) {
+	t.Run("assignable to struct which has pointers", func(t *testing.T) {
+		r := bytes.NewReader(referenceTest.Binary)
+		dec := NewDecoder(r)
+
+		var v sampleSharedObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, &sampleObject{A: "s", B: 42}, v.X)
+		require.True(t, v.X == v.Y)
+	})
+
+	t.Run("assignable to interface{}", func(t *testing.T) {
+		r := bytes.NewReader(referenceTest.Binary)
+		dec := NewDecoder(r)
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+
+		m := v.(map[string]interface{})
+		require.Equal(t, map[string]interface{}{"a": "s", "b": float64(42)}, m["x"])
+		require.Equal(t,
+			reflect.ValueOf(m["x"]).Pointer(),
+			reflect.ValueOf(m["y"]).Pointer(),
+		)
+	})
+
+	t.Run("cyclic object", func(t *testing.T) {
+		bin := []byte{
+			0x03,       // Object Marker
+			0x00, 0x04, // - Length(4: u16) BigEndian
+			0x73, 0x65, 0x6c, 0x66, //   Key(self: []byte)
+			0x07,       //   - Reference Marker
+			0x00, 0x00, //     Index(0: u16) BigEndian
+			0x00, 0x00, // - Length(0: u16) BigEndian
+			0x09, //   - ObjectEndMarker
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+
+		m := v.(map[string]interface{})
+		require.Equal(t,
+			reflect.ValueOf(m).Pointer(),
+			reflect.ValueOf(m["self"]).Pointer(),
+		)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		bin := []byte{0x07, 0x00, 0x00}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeTypedObject(t *testing.T) {
+	t.Run("assignable to struct", func(t *testing.T) {
+		r := bytes.NewReader(typedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v sampleUser
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, sampleUser{Name: "a", Age: 20}, v)
+	})
+
+	t.Run("assignable to map", func(t *testing.T) {
+		r := bytes.NewReader(typedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v map[string]interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, map[string]interface{}{"name": "a", "age": float64(20)}, v)
+	})
+
+	t.Run("assignable to TypedObject", func(t *testing.T) {
+		r := bytes.NewReader(typedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v TypedObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, "com.example.User", v.ClassName)
+		require.Equal(t, map[string]interface{}{
+			"name": &Value{Kind: MarkerString, String: "a"},
+			"age":  &Value{Kind: MarkerNumber, Number: 20},
+		}, v.Fields)
+	})
+
+	t.Run("unregistered one is re-encoded as is", func(t *testing.T) {
+		r := bytes.NewReader(unregisteredTypedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+		err = enc.Encode(v)
+		require.Nil(t, err)
+		require.Equal(t, unregisteredTypedObjectTest.Binary, buf.Bytes())
+	})
+
+	t.Run("nested objects are re-encoded as is", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+		err := enc.Encode(TypedObject{
+			ClassName: "x.Y",
+			Fields: map[string]interface{}{
+				"o": OrderedObject{{"d", float64(1)}, {"c", float64(2)}, {"b", float64(3)}, {"a", float64(4)}},
+				"e": OrderedECMAArray{{"z", "1"}, {"y", "2"}, {"x", "3"}, {"w", "4"}},
+				"l": []interface{}{OrderedObject{{"q", true}, {"p", false}, {"r", true}}},
+			},
+			keys: []string{"o", "e", "l"},
+		})
+		require.Nil(t, err)
+		bin := buf.Bytes()
+
+		for i := 0; i < 10; i++ {
+			dec := NewDecoder(bytes.NewReader(bin))
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.Nil(t, err)
+
+			buf := bytes.NewBuffer([]byte{})
+			enc := NewEncoder(buf)
+			err = enc.Encode(v)
+			require.Nil(t, err)
+			require.Equal(t, bin, buf.Bytes())
+		}
+	})
+
+	t.Run("fields are re-encoded as is", func(t *testing.T) {
+		fieldCases := []struct {
+			Name  string
+			Value []byte
+		}{
+			{
+				Name: "ECMA Array with a count which differs from the number of properties",
+				Value: []byte{
+					// ECMA Array Marker, Count(0: u32) BigEndian
+					0x08, 0x00, 0x00, 0x00, 0x00,
+					// - Key(k), Number(1)
+					0x00, 0x01, 0x6b, 0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					// - ObjectEndMarker
+					0x00, 0x00, 0x09,
+				},
+			},
+			{
+				Name: "Date with a time zone",
+				Value: []byte{
+					// Date Marker, Value(1000: double) BigEndian, TZ(480: s16) BigEndian
+					0x0b, 0x40, 0x8f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xe0,
+				},
+			},
+			{
+				Name: "Date with a fraction of milliseconds",
+				Value: []byte{
+					// Date Marker, Value(1000.5: double) BigEndian, TZ(0: s16) BigEndian
+					0x0b, 0x40, 0x8f, 0x44, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+			{
+				Name: "Reference",
+				Value: []byte{
+					// Reference Marker, Index(0: u16) BigEndian which refers the Typed Object
+					0x07, 0x00, 0x00,
+				},
+			},
+			{
+				Name: "Object with keys which are not sorted",
+				Value: []byte{
+					// Object Marker
+					0x03,
+					// - Key(b), Boolean(true), Key(a), Undefined
+					0x00, 0x01, 0x62, 0x01, 0x01, 0x00, 0x01, 0x61, 0x06,
+					// - ObjectEndMarker
+					0x00, 0x00, 0x09,
+				},
+			},
+		}
+
+		for _, fc := range fieldCases {
+			fc := fc // capture
+
+			t.Run(fc.Name, func(t *testing.T) {
+				bin := []byte{
+					// TypedObject Marker, ClassName(x.Y)
+					0x10, 0x00, 0x03, 0x78, 0x2e, 0x59,
+					// - Key(v)
+					0x00, 0x01, 0x76,
+				}
+				bin = append(bin, fc.Value...)
+				bin = append(bin, 0x00, 0x00, 0x09) // ObjectEndMarker
+
+				var v interface{}
+				err := NewDecoder(bytes.NewReader(bin)).Decode(&v)
+				require.Nil(t, err)
+
+				buf := bytes.NewBuffer([]byte{})
+				err = NewEncoder(buf).Encode(v)
+				require.Nil(t, err)
+				require.Equal(t, bin, buf.Bytes())
+			})
+		}
+	})
+
+	t.Run("NOT assignable to int", func(t *testing.T) {
+		r := bytes.NewReader(typedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v int
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeUndefined(t *testing.T) {
+	bin := []byte{0x06} // Undefined
+
+	t.Run("assignable to interface{}", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v interface{} = 42
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, Undefined, v)
+	})
+
+	t.Run("assignable to UndefinedType", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v UndefinedType
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, Undefined, v)
+	})
+
+	t.Run("assignable to pointer", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		v := intPtr(42)
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, (*int)(nil), v)
+	})
+
+	t.Run("assignable to map", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		v := map[string]int{"a": 1}
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int(nil), v)
+	})
+
+	t.Run("assignable to slice", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		v := []int{1}
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, []int(nil), v)
+	})
+
+	t.Run("NOT assignable to int", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v int
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeXMLDocument(t *testing.T) {
+	bin := []byte{
+		0x0f,                   // XMLDocument Marker
+		0x00, 0x00, 0x00, 0x08, // Length(8: u32) BigEndian
+		0x3c, 0x61, 0x3e, 0x62, 0x3c, 0x2f, 0x61, 0x3e, // Value(<a>b</a>: []byte)
+	}
+
+	t.Run("assignable to XMLDocument", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v XMLDocument
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, XMLDocument("<a>b</a>"), v)
+	})
+
+	t.Run("assignable to string", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v string
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, "<a>b</a>", v)
+	})
+
+	t.Run("assignable to xml unmarshalable struct", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		type doc struct {
+			XMLName xml.Name `xml:"a"`
+			Text    string   `xml:",chardata"`
+		}
+
+		var v doc
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, "b", v.Text)
+	})
+
+	t.Run("NOT assignable to struct which has different structure", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		type doc struct {
+			XMLName xml.Name `xml:"other"`
+		}
+
+		var v doc
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+
+	t.Run("assignable to xml.Unmarshaler", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v xmlText
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, xmlText("b"), v)
+	})
+
+	for _, v := range []interface{}{new(int), new(map[string]interface{}), new([]int)} {
+		v := v // capture
+
+		t.Run(fmt.Sprintf("NOT assignable to %T", v), func(t *testing.T) {
+			r := bytes.NewReader(bin)
+			dec := NewDecoder(r)
+
+			err := dec.Decode(v)
+			require.IsType(t, &NotAssignableError{}, err)
+		})
+	}
+}
+
+// xmlText A string-kind type which implements xml.Unmarshaler to take the text of the element
+type xmlText string
+
+func (x *xmlText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	*x = xmlText(s)
+
+	return nil
+}
+
+func TestDecodeDateTimeZone(t *testing.T) {
+	t.Run("with time zone", func(t *testing.T) {
+		r := bytes.NewReader(dateWithTimeZoneBinary)
+		dec := NewDecoder(r, WithDecodeTimeZone())
+
+		var v time.Time
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.True(t, time.Unix(0x1234, 0).Equal(v))
+
+		_, offset := v.Zone()
+		require.Equal(t, 9*60*60, offset)
+	})
+
+	t.Run("without time zone", func(t *testing.T) {
+		r := bytes.NewReader(dateWithTimeZoneBinary)
+		dec := NewDecoder(r)
+
+		var v time.Time
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, time.Unix(0x1234, 0).In(time.UTC), v)
+	})
+
+	t.Run("assignable to Date", func(t *testing.T) {
+		r := bytes.NewReader(dateWithTimeZoneBinary)
+		dec := NewDecoder(r, WithDecodeTimeZone())
+
+		var v Date
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.Equal(t, Date{Millis: 0x1234 * 1000, TZ: 540}, v)
+	})
+}
+
+func TestDecodeDateMillis(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Millis float64
+		Time   time.Time
+		Error  bool
+	}{
+		{Name: "milliseconds", Millis: 0x1234*1000 + 123, Time: time.Unix(0x1234, 123*int64(time.Millisecond))},
+		{Name: "fraction", Millis: 0x1234*1000 + 1.5, Time: time.Unix(0x1234, 1500*int64(time.Microsecond))},
+		{Name: "negative", Millis: -1001, Time: time.Unix(-2, 999*int64(time.Millisecond))},
+		{Name: "negative fraction", Millis: -1.5, Time: time.Unix(0, -1500*int64(time.Microsecond))},
+		{Name: "NaN", Millis: math.NaN(), Error: true},
+		{Name: "+Infinity", Millis: math.Inf(1), Error: true},
+		{Name: "-Infinity", Millis: math.Inf(-1), Error: true},
+		{Name: "too large", Millis: 1e300, Error: true},
+		{Name: "too small", Millis: -1e300, Error: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(dateBinary(tc.Millis, 0))
+			dec := NewDecoder(r)
+
+			var v time.Time
+			err := dec.Decode(&v)
+			if tc.Error {
+				require.IsType(t, &InvalidDateError{}, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Time.In(time.UTC), v)
+		})
+	}
+
+	t.Run("NaN is assignable to Date", func(t *testing.T) {
+		r := bytes.NewReader(dateBinary(math.NaN(), 0))
+		dec := NewDecoder(r)
+
+		var v Date
+		err := dec.Decode(&v)
+		require.NoError(t, err)
+		require.True(t, math.IsNaN(v.Millis))
+	})
+}
+
+func TestDecodeTaggedObject(t *testing.T) {
+	t.Run("assignable to struct", func(t *testing.T) {
+		r := bytes.NewReader(taggedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v sampleTaggedObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, taggedObjectTest.Value, v)
+	})
+
+	t.Run("skipped and unexported fields are not decoded", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+		err := enc.Encode(map[string]interface{}{
+			"Skipped": "s",
+			"hidden":  "h",
+			"Name":    "n", // field name is not used if the tag has a name
+		})
+		require.Nil(t, err)
+
+		r := bytes.NewReader(buf.Bytes())
+		dec := NewDecoder(r)
+
+		var v sampleTaggedObject
+		err = dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, sampleTaggedObject{}, v)
+	})
+
+	t.Run("string option requires String", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+		err := enc.Encode(map[string]interface{}{
+			"Count": 42,
+		})
+		require.Nil(t, err)
+
+		r := bytes.NewReader(buf.Bytes())
+		dec := NewDecoder(r)
+
+		var v sampleTaggedObject
+		err = dec.Decode(&v)
+		require.Error(t, err)
+	})
+
+	t.Run("string option requires numeric String", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+		err := enc.Encode(map[string]interface{}{
+			"Count": "a",
+		})
+		require.Nil(t, err)
+
+		r := bytes.NewReader(buf.Bytes())
+		dec := NewDecoder(r)
+
+		var v sampleTaggedObject
+		err = dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeEmbeddedObject(t *testing.T) {
+	t.Run("assignable to struct", func(t *testing.T) {
+		r := bytes.NewReader(embeddedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v sampleEmbeddedObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, embeddedObjectTest.Value, v)
+	})
+
+	t.Run("embedded pointer is allocated", func(t *testing.T) {
+		type Base struct {
+			TransactionID int `amf0:"transactionId"`
+		}
+		type embeddedPtr struct {
+			*Base
+			Name string `amf0:"name"`
+		}
+
+		r := bytes.NewReader(embeddedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v embeddedPtr
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, embeddedPtr{Base: &Base{TransactionID: 1}, Name: "a"}, v)
+	})
+
+	t.Run("embedded pointer to unexported struct cannot be allocated", func(t *testing.T) {
+		type embeddedPtr struct {
+			*sampleBase
+			Name string `amf0:"name"`
+		}
+
+		r := bytes.NewReader(embeddedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v embeddedPtr
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeUnmarshaler(t *testing.T) {
+	t.Run("assignable to struct", func(t *testing.T) {
+		r := bytes.NewReader(marshalerObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		k := sampleStreamKey("will be nil")
+		v := sampleMarshalerObject{
+			Ptr: &k,
+		}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, marshalerObjectTest.Value, v)
+		require.Equal(t, 0, r.Len())
+	})
+
+	t.Run("nil pointer is allocated", func(t *testing.T) {
+		bin := []byte{0x02, 0x00, 0x05, 0x6b, 0x65, 0x79, 0x3a, 0x6b}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v *sampleStreamKey
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, sampleStreamKey("k"), *v)
+	})
+
+	t.Run("raw bytes", func(t *testing.T) {
+		bin := []byte{0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v sampleFlags
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, sampleFlags(5), v)
+	})
+
+	t.Run("error from Unmarshaler", func(t *testing.T) {
+		bin := []byte{0x05}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v sampleFlags
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeTextUnmarshaler(t *testing.T) {
+	t.Run("net.IP", func(t *testing.T) {
+		bin := append([]byte{0x02, 0x00, 0x09}, "127.0.0.1"...)
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v net.IP
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.True(t, net.IPv4(127, 0, 0, 1).Equal(v))
+	})
+
+	t.Run("map which has TextUnmarshaler keys", func(t *testing.T) {
+		r := bytes.NewReader(textMapTest.Binary)
+		dec := NewDecoder(r)
+
+		var v map[sampleEnum]sampleEnum
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, textMapTest.Value, v)
+	})
+
+	t.Run("ECMA array which has TextUnmarshaler keys", func(t *testing.T) {
+		bin := []byte{
+			0x08,                   // ECMA Array Marker
+			0x00, 0x00, 0x00, 0x01, // Associative count(1: u32) BigEndian
+			0x00, 0x03, 0x6f, 0x6e, 0x65, // Key(one)
+			0x02, 0x00, 0x04, 0x7a, 0x65, 0x72, 0x6f, // String(zero)
+			0x00, 0x00, 0x09, // End
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v map[sampleEnum]sampleEnum
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, map[sampleEnum]sampleEnum{1: 0}, v)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		bin := []byte{
+			0x03,                         // Object Marker
+			0x00, 0x03, 0x74, 0x77, 0x6f, // Key(two)
+			0x05,             // Null
+			0x00, 0x00, 0x09, // End
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v map[sampleEnum]interface{}
+		err := dec.Decode(&v)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeBinaryUnmarshaler(t *testing.T) {
+	t.Run("from Strict Array", func(t *testing.T) {
+		r := bytes.NewReader(binaryTest.Binary)
+		dec := NewDecoder(r)
+
+		var v sampleBinary
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, binaryTest.Value, v)
+	})
+
+	t.Run("from String", func(t *testing.T) {
+		bin := []byte{0x02, 0x00, 0x02, 0x01, 0x02}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var v sampleBinary
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, binaryTest.Value, v)
+	})
+}
+
+func TestDecodeOrderedObject(t *testing.T) {
+	for _, tc := range []testCase{orderedObjectTest, orderedECMAArrayTest} {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			r := bytes.NewReader(tc.Binary)
+			dec := NewDecoder(r, WithOrderedObjects())
+
+			var v interface{}
+			err := dec.Decode(&v)
+			require.Nil(t, err)
+			require.Equal(t, tc.Value, v)
+			require.Equal(t, 0, r.Len())
+		})
+	}
+
+	t.Run("typed", func(t *testing.T) {
+		r := bytes.NewReader(orderedObjectTest.Binary)
+		dec := NewDecoder(r) // The option is not required for typed values
+
+		var v OrderedObject
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, orderedObjectTest.Value, v)
+
+		app, ok := v.Get("app")
+		require.True(t, ok)
+		require.Equal(t, "live", app)
+	})
+
+	t.Run("without option", func(t *testing.T) {
+		r := bytes.NewReader(orderedObjectTest.Binary)
+		dec := NewDecoder(r)
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, map[string]interface{}{
+			"app":   "live",
+			"tcUrl": "rtmp://x",
+			"fpad":  false,
+		}, v)
+	})
+
+	t.Run("reference", func(t *testing.T) {
+		bin := []byte{
+			0x0a,                   // Strict Array Marker
+			0x00, 0x00, 0x00, 0x02, // Array length (2: u32) BigEndian
+			0x03,             // Object Marker (reference index 1)
+			0x00, 0x01, 0x61, // Key(a)
+			0x05,             // Null
+			0x00, 0x00, 0x09, // End
+			0x07, 0x00, 0x01, // Reference(1)
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r, WithOrderedObjects())
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+
+		o := OrderedObject{{Key: "a", Value: nil}}
+		require.Equal(t, []interface{}{o, o}, v)
+	})
+
+	t.Run("cyclic reference", func(t *testing.T) {
+		bin := []byte{
+			0x03,             // Object Marker (reference index 0)
+			0x00, 0x01, 0x61, // Key(a)
+			0x07, 0x00, 0x00, // Reference(0)
+			0x00, 0x00, 0x09, // End
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r, WithOrderedObjects())
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.IsType(t, &DecodeError{}, err)
+	})
+}
+
+func TestOrderedObjectSet(t *testing.T) {
+	var o OrderedObject
+	o.Set("b", 1)
+	o.Set("a", 2)
+	o.Set("b", 3)
+	require.Equal(t, OrderedObject{{Key: "b", Value: 3}, {Key: "a", Value: 2}}, o)
+
+	_, ok := o.Get("c")
+	require.False(t, ok)
+}
+
+func TestDecoderOptions(t *testing.T) {
+	decodeFuncs := []struct {
+		name   string
+		decode func(dec *Decoder) error
+	}{
+		{
+			name: "Decode",
+			decode: func(dec *Decoder) error {
+				var v interface{}
+				return dec.Decode(&v)
+			},
+		},
+		{
+			name: "Value",
+			decode: func(dec *Decoder) error {
+				var v Value
+				return dec.Decode(&v)
+			},
+		},
+		{
+			name: "Skip",
+			decode: func(dec *Decoder) error {
+				return dec.Skip()
+			},
+		},
+		{
+			name: "Token",
+			decode: func(dec *Decoder) error {
+				level := 0
+				for {
+					tok, err := dec.Token()
+					if err != nil {
+						return err
+					}
+
+					switch tok.Kind {
+					case TokenStartObject, TokenStartArray:
+						level++
+					case TokenEndObject, TokenEndArray:
+						level--
+					}
+					if level == 0 && tok.Kind != TokenKey {
+						return nil
+					}
+				}
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		bin    []byte
+		opts   DecoderOptions
+		limit  string
+		within DecoderOptions
+	}{
+		{
+			name: "MaxDepth",
+			bin: []byte{
+				0x0a, 0x00, 0x00, 0x00, 0x01, // StrictArray(length 1)
+				0x0a, 0x00, 0x00, 0x00, 0x01, // - StrictArray(length 1)
+				0x05, //                         - Null
+			},
+			opts:   DecoderOptions{MaxDepth: 2},
+			limit:  "MaxDepth",
+			within: DecoderOptions{MaxDepth: 3},
+		},
+		{
+			name:   "MaxArrayLength",
+			bin:    []byte{0x0a, 0x7f, 0xff, 0xff, 0xff}, // StrictArray(length 2147483647) without elements
+			opts:   DecoderOptions{MaxArrayLength: 100},
+			limit:  "MaxArrayLength",
+			within: DecoderOptions{MaxArrayLength: math.MaxInt32},
+		},
+		{
+			name:   "MaxArrayLength(AMF3)",
+			bin:    []byte{0x11, 0x09, 0x8f, 0x51}, // AVMPlusObject, AMF3 Array(length 1000) without elements
+			opts:   DecoderOptions{MaxArrayLength: 100},
+			limit:  "MaxArrayLength",
+			within: DecoderOptions{MaxArrayLength: 1000},
+		},
+		{
+			name:   "MaxStringLength",
+			bin:    []byte{0x0c, 0x7f, 0xff, 0xff, 0xff}, // LongString(length 2147483647) without bytes
+			opts:   DecoderOptions{MaxStringLength: 1024},
+			limit:  "MaxStringLength",
+			within: DecoderOptions{MaxStringLength: math.MaxInt32},
+		},
+		{
+			name: "MaxObjectKeys",
+			bin: []byte{
+				0x03,                   // Object Marker
+				0x00, 0x01, 0x61, 0x05, // - Key(a), Null
+				0x00, 0x01, 0x62, 0x05, // - Key(b), Null
+				0x00, 0x00, 0x09, //       - End
+			},
+			opts:   DecoderOptions{MaxObjectKeys: 1},
+			limit:  "MaxObjectKeys",
+			within: DecoderOptions{MaxObjectKeys: 2},
+		},
+		{
+			name:   "MaxBytes",
+			bin:    []byte{0x02, 0x00, 0x05, 0x68, 0x65, 0x6c, 0x6c, 0x6f}, // String(hello)
+			opts:   DecoderOptions{MaxBytes: 7},
+			limit:  "MaxBytes",
+			within: DecoderOptions{MaxBytes: 8},
+		},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, f := range decodeFuncs {
+				f := f // capture
+
+				t.Run(f.name, func(t *testing.T) {
+					dec := NewDecoder(bytes.NewReader(tc.bin), WithDecoderOptions(tc.opts))
+
+					err := f.decode(dec)
+					require.IsType(t, &LimitExceededError{}, err)
+					require.Equal(t, tc.limit, err.(*LimitExceededError).Limit)
+				})
+			}
+		})
+
+		if tc.within.MaxArrayLength == 0 && tc.within.MaxStringLength == 0 { // Inputs of other cases are truncated
+			t.Run(tc.name+" within limits", func(t *testing.T) {
+				for _, f := range decodeFuncs {
+					dec := NewDecoder(bytes.NewReader(tc.bin), WithDecoderOptions(tc.within))
+
+					err := f.decode(dec)
+					require.Nil(t, err, f.name)
+				}
+			})
+		}
+	}
+
+	t.Run("StrictArray is not allocated beyond MaxBytes", func(t *testing.T) {
+		bin := []byte{0x0a, 0x7f, 0xff, 0xff, 0xff} // StrictArray(length 2147483647) without elements
+		dec := NewDecoder(bytes.NewReader(bin), WithDecoderOptions(DecoderOptions{MaxBytes: 1024}))
+
+		var v interface{}
+		err := dec.Decode(&v)
+		require.IsType(t, &LimitExceededError{}, err)
+		require.Equal(t, "MaxBytes", err.(*LimitExceededError).Limit)
+	})
+
+	t.Run("MaxBytes is reset", func(t *testing.T) {
+		bin := []byte{0x00, 0x40, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00} // Number: 10
+		dec := NewDecoder(bytes.NewReader(bin), WithDecoderOptions(DecoderOptions{MaxBytes: 9}))
+
+		var v float64
+		err := dec.Decode(&v)
+		require.Nil(t, err)
+
+		dec.Reset(bytes.NewReader(bin))
+		err = dec.Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, float64(10), v)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		bin := []byte{0x0a, 0x7f, 0xff, 0xff, 0xff} // StrictArray(length 2147483647) without elements
+		dec := NewDecoder(bytes.NewReader(bin), WithDecoderOptions(DecoderOptions{}))
+
+		err := dec.Skip()
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+}
+
+func TestDecodeDepthBoundary(t *testing.T) {
+	number := []byte{0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00} // Number(1)
+	object := []byte{
+		0x03,                   // Object Marker
+		0x00, 0x01, 0x78, 0x05, // - Key(x), Null
+		0x00, 0x00, 0x09, //       - End
+	}
+	nestedObject := []byte{
+		0x03,             // Object Marker
+		0x00, 0x01, 0x78, // - Key(x)
+		0x03,                   //   Object Marker
+		0x00, 0x01, 0x61, 0x05, //   - Key(a), Null
+		0x00, 0x00, 0x09, //         - End
+		0x00, 0x00, 0x09, //       - End
+	}
+	avmPlusArray := []byte{0x11, 0x09, 0x03, 0x01, 0x01} // AVMPlusObject, AMF3 Array(length 1), - Null
+
+	type rawField struct {
+		X RawMessage `amf0:"x"`
+	}
+
+	for _, tc := range []struct {
+		name   string
+		bin    []byte
+		target func() interface{}
+		depth  int // the minimum MaxDepth which accepts the value
+	}{
+		{"Number into RawMessage", number, func() interface{} { return &RawMessage{} }, 1},
+		{"Number into Value", number, func() interface{} { return &Value{} }, 1},
+		{"Object into interface{}", object, func() interface{} { return new(interface{}) }, 2},
+		{"Object into RawMessage", object, func() interface{} { return &RawMessage{} }, 2},
+		{"Object into Value", object, func() interface{} { return &Value{} }, 2},
+		{"Object into RawMessage field", nestedObject, func() interface{} { return &rawField{} }, 3},
+		{"AVMPlusObject into interface{}", avmPlusArray, func() interface{} { return new(interface{}) }, 2},
+		{"AVMPlusObject into RawMessage", avmPlusArray, func() interface{} { return &RawMessage{} }, 2},
+		{"AVMPlusObject into Value", avmPlusArray, func() interface{} { return &Value{} }, 2},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			dec := NewDecoder(bytes.NewReader(tc.bin), WithDecoderOptions(DecoderOptions{MaxDepth: tc.depth}))
+			err := dec.Decode(tc.target())
+			require.Nil(t, err)
+
+			if tc.depth > 1 {
+				dec := NewDecoder(bytes.NewReader(tc.bin), WithDecoderOptions(DecoderOptions{MaxDepth: tc.depth - 1}))
+				err := dec.Decode(tc.target())
+				require.IsType(t, &LimitExceededError{}, err)
+			}
+		})
+	}
+}
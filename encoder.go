@@ -8,6 +8,7 @@
 package amf0
 
 import (
+	"encoding"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -15,33 +16,214 @@ import (
 	"reflect"
 	"sort"
 	"time"
+
+	"github.com/yutopp/go-amf0/internal/shared"
 )
 
 // Encoder Encode objects in Golang into AMF0 and writes to the writer
 type Encoder struct {
-	w        io.Writer
-	sortKeys bool
+	w       io.Writer
+	keyLess func(a, b string) bool
+
+	useReferences bool
+	refs          map[shared.ReferenceKey]uint16
+	numObjects    int
+
+	useTimeZone  bool
+	dateRounding DateRounding
+
+	validateRawMessage bool
+
+	amf3 AMF3Codec
+
+	frames []writerFrame // a stack of objects and arrays opened by Begin methods
+	depth  int           // a nesting level of Encode calls
+}
+
+// Marshaler The interface implemented by types which can encode themselves into AMF0
+// MarshalAMF0 must write exactly one value by methods of the encoder, such as Encode and Write.
+type Marshaler interface {
+	MarshalAMF0(enc *Encoder) error
+}
+
+var (
+	marshalerType       = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// EncoderOption An option for Encoder
+type EncoderOption func(*Encoder)
+
+// WithSortedKeys Encode keys of maps in lexicographical order to make outputs deterministic
+func WithSortedKeys() EncoderOption {
+	return WithKeyLess(func(a, b string) bool {
+		return a < b
+	})
+}
+
+// WithKeyPriority Encode the specified keys of maps first in the specified order, and then the other keys in lexicographical order
+func WithKeyPriority(keys ...string) EncoderOption {
+	return WithKeyLess(shared.KeyPriority(keys...))
+}
+
+// WithKeyLess Encode keys of maps in the order defined by the less function
+func WithKeyLess(less func(a, b string) bool) EncoderOption {
+	return func(enc *Encoder) {
+		enc.keyLess = less
+	}
+}
+
+// WithReferences Encode pointers, maps and slices which are already encoded as references.
+// References are resolved within values encoded between resets, thus Reset should be called for each message.
+func WithReferences() EncoderOption {
+	return func(enc *Encoder) {
+		enc.useReferences = true
+	}
+}
+
+// WithEncodeTimeZone Write offsets of locations of time.Time values into time zone fields of Dates.
+// The time zone field is written as an offset in minutes east of UTC. Otherwise, the field is always 0.
+func WithEncodeTimeZone() EncoderOption {
+	return func(enc *Encoder) {
+		enc.useTimeZone = true
+	}
+}
+
+// DateRounding A policy to encode sub-millisecond parts of time.Time values
+type DateRounding int
+
+const (
+	// DateRoundingReject Fails to encode time.Time values which have sub-millisecond parts. This is the default
+	DateRoundingReject DateRounding = iota
+	// DateRoundingTruncate Truncates sub-millisecond parts toward the past
+	DateRoundingTruncate
+	// DateRoundingRound Rounds sub-millisecond parts to the nearest millisecond, rounding half up
+	DateRoundingRound
+	// DateRoundingFraction Keeps sub-millisecond parts as fractions of milliseconds
+	DateRoundingFraction
+)
+
+// WithDateRounding Specify the policy to encode sub-millisecond parts of time.Time values
+func WithDateRounding(r DateRounding) EncoderOption {
+	return func(enc *Encoder) {
+		enc.dateRounding = r
+	}
+}
+
+// WithValidateRawMessage Check that RawMessage and AVMPlusObject values are single complete values before writing them
+func WithValidateRawMessage() EncoderOption {
+	return func(enc *Encoder) {
+		enc.validateRawMessage = true
+	}
+}
+
+// WithEncodeAMF3 Encode AMF3Value values by the AMF3 codec
+func WithEncodeAMF3(c AMF3Codec) EncoderOption {
+	return func(enc *Encoder) {
+		enc.amf3 = c
+	}
 }
 
 // NewEncoder Create a new instance of Encoder
-func NewEncoder(w io.Writer) *Encoder {
-	return &Encoder{
+func NewEncoder(w io.Writer, opts ...EncoderOption) *Encoder {
+	enc := &Encoder{
 		w: w,
 	}
+	for _, opt := range opts {
+		opt(enc)
+	}
+
+	return enc
 }
 
 // Encode Encode objects
 func (enc *Encoder) Encode(v interface{}) error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	enc.depth++
+	defer func() { enc.depth-- }()
+
 	rv := reflect.ValueOf(v)
 	return enc.encode(rv)
 }
 
+// Write Write raw bytes to the writer. It is intended to be used by Marshaler implementations to write raw AMF0 values.
+func (enc *Encoder) Write(p []byte) (int, error) {
+	return enc.w.Write(p)
+}
+
 // Reset Reset a state of the encoder
 func (enc *Encoder) Reset(w io.Writer) {
 	enc.w = w
+	enc.refs = nil
+	enc.numObjects = 0
+	enc.frames = nil
 }
 
 func (enc *Encoder) encode(rv reflect.Value) error {
+	if !enc.useReferences {
+		return enc.encodeValue(rv)
+	}
+
+	key, ok := shared.ReferenceKeyOf(rv)
+	if !ok {
+		return enc.encodeValue(rv)
+	}
+
+	if index, ok := enc.refs[key]; ok {
+		return enc.encodeReference(index)
+	}
+
+	// Register the value before encoding children to support cyclic values
+	index := enc.numObjects
+	if index <= math.MaxUint16 {
+		if enc.refs == nil {
+			enc.refs = make(map[shared.ReferenceKey]uint16)
+		}
+		enc.refs[key] = uint16(index)
+	}
+
+	if err := enc.encodeValue(rv); err != nil {
+		return err
+	}
+
+	if enc.numObjects == index {
+		// The value was not encoded as a complex object, thus it cannot be referenced
+		delete(enc.refs, key)
+	}
+
+	return nil
+}
+
+func (enc *Encoder) encodeValue(rv reflect.Value) error {
+	if m, ok := shared.InterfaceOf(rv, marshalerType); ok {
+		return m.(Marshaler).MarshalAMF0(enc)
+	}
+
+	// time.Time implements encoding.TextMarshaler, however it should be encoded as Date.
+	// Pointers are checked after they are dereferenced not to encode *time.Time as String.
+	if rv.IsValid() && rv.Kind() != reflect.Ptr && rv.Type() != reflect.TypeOf(time.Time{}) {
+		if m, ok := shared.InterfaceOf(rv, textMarshalerType); ok {
+			text, err := m.(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return err
+			}
+			return enc.encodeString(reflect.ValueOf(string(text)))
+		}
+
+		if m, ok := shared.InterfaceOf(rv, binaryMarshalerType); ok {
+			// Encoded in the same way as []byte
+			data, err := m.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				return err
+			}
+			return enc.encode(reflect.ValueOf(data))
+		}
+	}
+
 	switch rv.Kind() {
 	case reflect.Ptr:
 		return enc.encode(rv.Elem())
@@ -57,6 +239,9 @@ func (enc *Encoder) encode(rv reflect.Value) error {
 		return enc.encodeBoolean(rv)
 
 	case reflect.String:
+		if rv.Type() == reflect.TypeOf(XMLDocument("")) {
+			return enc.encodeXMLDocument(rv)
+		}
 		return enc.encodeString(rv)
 
 	case reflect.Map:
@@ -69,13 +254,19 @@ func (enc *Encoder) encode(rv reflect.Value) error {
 		if rv.IsNil() {
 			return enc.encodeNull()
 		}
+		switch rv.Type() {
+		case reflect.TypeOf(OrderedObject{}):
+			return enc.encodeOrderedObject(rv)
+		case reflect.TypeOf(OrderedECMAArray{}):
+			return enc.encodeOrderedECMAArray(rv)
+		}
 		return enc.encodeStrictArray(rv)
 
 	case reflect.Interface:
 		if rv.IsNil() {
 			return enc.encodeNull()
 		}
-		return enc.Encode(rv.Interface())
+		return enc.encode(rv.Elem())
 
 	case reflect.Invalid:
 		return enc.encodeNull()
@@ -84,9 +275,18 @@ func (enc *Encoder) encode(rv reflect.Value) error {
 		switch rv.Type() {
 		case reflect.TypeOf(ObjectEnd):
 			return enc.encodeObjectEnd()
+		case reflect.TypeOf(Undefined):
+			return enc.encodeUndefined()
 		case reflect.TypeOf(time.Time{}):
 			return enc.encodeDate(rv)
+		case reflect.TypeOf(Date{}):
+			return enc.encodeRawDate(rv)
+		case reflect.TypeOf(TypedObject{}):
+			return enc.encodeGenericTypedObject(rv)
 		default:
+			if className, ok := lookupRegisteredClassName(rv.Type()); ok {
+				return enc.encodeTypedObject(className, rv)
+			}
 			return enc.encodeObject(rv)
 		}
 
@@ -109,22 +309,35 @@ func (enc *Encoder) encodeObject(rv reflect.Value) error {
 	if err := enc.writeU8(uint8(MarkerObject)); err != nil {
 		return err
 	}
+	enc.numObjects++
 
-	ty := rv.Type()
-	numFields := rv.NumField()
-	for i := 0; i < numFields; i++ {
-		fieldType := ty.Field(i)
+	return enc.encodeObjectProperties(rv)
+}
 
-		key := fieldType.Tag.Get("amf0")
-		if key == "" {
-			key = fieldType.Name
+func (enc *Encoder) encodeObjectProperties(rv reflect.Value) error {
+	fields := cachedTypeFields(rv.Type())
+	for i := range fields.List {
+		f := &fields.List[i]
+
+		value, ok := fieldByIndex(rv, f.Index)
+		if !ok {
+			continue
+		}
+		if f.OmitEmpty && isEmptyValue(value) {
+			continue
 		}
 
-		if err := enc.writeUTF8(key); err != nil {
+		if err := enc.writeUTF8(f.Name); err != nil {
 			return err
 		}
 
-		value := rv.Field(i)
+		if f.AsString {
+			if err := enc.encodeStringified(value); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if err := enc.encode(value); err != nil {
 			return err
 		}
@@ -133,6 +346,19 @@ func (enc *Encoder) encodeObject(rv reflect.Value) error {
 	return enc.encodeObjectEnd()
 }
 
+// encodeStringified Encodes a boolean or numeric value as a String
+func (enc *Encoder) encodeStringified(rv reflect.Value) error {
+	s, err := shared.FormatStringified(rv)
+	if err != nil {
+		return err
+	}
+
+	if err := enc.writeU8(uint8(MarkerString)); err != nil {
+		return err
+	}
+	return enc.writeUTF8(s)
+}
+
 func (enc *Encoder) encodeNumber(rv reflect.Value) error {
 	if err := enc.writeU8(uint8(MarkerNumber)); err != nil {
 		return err
@@ -184,33 +410,9 @@ func (enc *Encoder) encodeMapAsObject(rv reflect.Value) error {
 	if err := enc.writeU8(uint8(MarkerObject)); err != nil {
 		return err
 	}
+	enc.numObjects++
 
-	keys := rv.MapKeys()
-	if enc.sortKeys {
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-	}
-
-	for _, key := range keys {
-		if key.Kind() != reflect.String {
-			return &UnexpectedKeyTypeError{
-				ActualKind: key.Kind(),
-				ExpectKind: reflect.String,
-			}
-		}
-
-		if err := enc.writeUTF8(key.String()); err != nil {
-			return err
-		}
-
-		value := rv.MapIndex(key)
-		if err := enc.encode(value); err != nil {
-			return err
-		}
-	}
-
-	return enc.encodeObjectEnd()
+	return enc.encodeMapProperties(rv)
 }
 
 //lint:ignore U1000 Maybe used in the future
@@ -222,40 +424,84 @@ func (enc *Encoder) encodeNull() error {
 	return enc.writeU8(uint8(MarkerNull))
 }
 
-//lint:ignore U1000 Maybe used in the future
-func (enc *Encoder) encodeUndefined(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: Undefined")
+func (enc *Encoder) encodeUndefined() error {
+	return enc.writeU8(uint8(MarkerUndefined))
 }
 
-//lint:ignore U1000 Maybe used in the future
-func (enc *Encoder) encodeReference(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: Reference")
+func (enc *Encoder) encodeReference(index uint16) error {
+	if err := enc.writeU8(uint8(MarkerReference)); err != nil {
+		return err
+	}
+
+	return enc.writeU16(index)
 }
 
 func (enc *Encoder) encodeMapAsECMAArray(rv reflect.Value) error {
 	if err := enc.writeU8(uint8(MarkerEcmaArray)); err != nil {
 		return err
 	}
+	enc.numObjects++
 
 	l := rv.Len()
 	if err := enc.writeU32(uint32(l)); err != nil {
 		return err
 	}
 
-	keys := rv.MapKeys()
-	if enc.sortKeys {
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
+	return enc.encodeMapProperties(rv)
+}
+
+func (enc *Encoder) encodeMapProperties(rv reflect.Value) error {
+	entries, err := shared.MapEntries(rv, enc.keyLess)
+	if err != nil {
+		return err
 	}
 
-	for _, key := range keys {
-		if err := enc.writeUTF8(key.String()); err != nil {
+	for _, e := range entries {
+		if err := enc.writeUTF8(e.Name); err != nil {
 			return err
 		}
 
-		value := rv.MapIndex(key)
-		if err := enc.encode(value); err != nil {
+		if err := enc.encode(e.Value); err != nil {
+			return err
+		}
+	}
+
+	return enc.encodeObjectEnd()
+}
+
+func (enc *Encoder) encodeOrderedObject(rv reflect.Value) error {
+	if err := enc.writeU8(uint8(MarkerObject)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	return enc.encodeKeyValues(rv.Interface().(OrderedObject))
+}
+
+func (enc *Encoder) encodeOrderedECMAArray(rv reflect.Value) error {
+	if err := enc.writeU8(uint8(MarkerEcmaArray)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	l := rv.Len()
+	if err := enc.writeU32(uint32(l)); err != nil {
+		return err
+	}
+
+	return enc.encodeKeyValues(rv.Interface().(OrderedECMAArray))
+}
+
+func (enc *Encoder) encodeKeyValues(kvs []KeyValue) error {
+	for _, kv := range kvs {
+		if kv.Key == "" {
+			return fmt.Errorf("key of property must not be empty")
+		}
+		if err := enc.writeUTF8(kv.Key); err != nil {
+			return err
+		}
+
+		if err := enc.encode(reflect.ValueOf(kv.Value)); err != nil {
 			return err
 		}
 	}
@@ -275,6 +521,7 @@ func (enc *Encoder) encodeStrictArray(rv reflect.Value) error {
 	if err := enc.writeU8(uint8(MarkerStrictArray)); err != nil {
 		return err
 	}
+	enc.numObjects++
 
 	if err := enc.writeU32(uint32(rv.Len())); err != nil {
 		return err
@@ -291,14 +538,16 @@ func (enc *Encoder) encodeStrictArray(rv reflect.Value) error {
 
 func (enc *Encoder) encodeDate(rv reflect.Value) error {
 	t := rv.Interface().(time.Time)
-	t = t.In(time.UTC) // Time zone is not supported yet, thus force convert to UTC. TODO: support time zone
-
-	if t.UnixNano()%int64(time.Millisecond) != 0 {
-		return fmt.Errorf("date time of nano sec is not supported: Expected = 0, Actual = %d", t.UnixNano()%int64(time.Millisecond))
-	}
 
-	unixMs := float64(t.UnixNano() / int64(time.Millisecond))
 	tz := int16(0x00)
+	if enc.useTimeZone {
+		_, offset := t.Zone()
+		tz = int16(offset / 60)
+	}
+	unixMs, err := shared.TimeToMillis(t, shared.DateRounding(enc.dateRounding))
+	if err != nil {
+		return err
+	}
 
 	if err := enc.writeU8(uint8(MarkerDate)); err != nil {
 		return err
@@ -311,8 +560,30 @@ func (enc *Encoder) encodeDate(rv reflect.Value) error {
 	return enc.writeS16(tz)
 }
 
+func (enc *Encoder) encodeRawDate(rv reflect.Value) error {
+	d := rv.Interface().(Date)
+
+	if err := enc.writeU8(uint8(MarkerDate)); err != nil {
+		return err
+	}
+
+	if err := enc.writeDouble(d.Millis); err != nil {
+		return err
+	}
+
+	return enc.writeS16(d.TZ)
+}
+
 func (enc *Encoder) encodeLongString(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: LongString")
+	s := rv.String()
+	if uint64(len(s)) > math.MaxUint32 {
+		return fmt.Errorf("too long string: Expected <= %d, Actual = %d", uint32(math.MaxUint32), len(s))
+	}
+
+	if err := enc.writeU8(uint8(MarkerLongString)); err != nil {
+		return err
+	}
+	return enc.writeUTF8Long(s)
 }
 
 //lint:ignore U1000 Maybe used in the future
@@ -325,14 +596,80 @@ func (enc *Encoder) encodeRecordSet(rv reflect.Value) error {
 	return fmt.Errorf("not implemented: RecordSet")
 }
 
-//lint:ignore U1000 Maybe used in the future
 func (enc *Encoder) encodeXMLDocument(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: XMLDocument")
+	s := rv.String()
+	if uint64(len(s)) > math.MaxUint32 {
+		return fmt.Errorf("too long XMLDocument: Expected <= %d, Actual = %d", uint32(math.MaxUint32), len(s))
+	}
+
+	if err := enc.writeU8(uint8(MarkerXMLDocument)); err != nil {
+		return err
+	}
+	return enc.writeUTF8Long(s)
 }
 
-//lint:ignore U1000 Maybe used in the future
-func (enc *Encoder) encodeTypedObject(rv reflect.Value) error {
-	return fmt.Errorf("not implemented: TypedObject")
+func (enc *Encoder) encodeTypedObject(className string, rv reflect.Value) error {
+	if err := enc.writeU8(uint8(MarkerTypedObject)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	if err := enc.writeUTF8(className); err != nil {
+		return err
+	}
+
+	return enc.encodeObjectProperties(rv)
+}
+
+func (enc *Encoder) encodeGenericTypedObject(rv reflect.Value) error {
+	to := rv.Interface().(TypedObject)
+	if to.ClassName == "" {
+		return fmt.Errorf("class name of TypedObject must not be empty")
+	}
+
+	if err := enc.writeU8(uint8(MarkerTypedObject)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	if err := enc.writeUTF8(to.ClassName); err != nil {
+		return err
+	}
+
+	// Keep the decoded order of keys to re-encode values as is
+	keys := make([]string, 0, len(to.Fields))
+	seen := make(map[string]bool, len(to.Fields))
+	for _, key := range to.keys {
+		if _, ok := to.Fields[key]; !ok || seen[key] {
+			continue
+		}
+		keys = append(keys, key)
+		seen[key] = true
+	}
+	numOrdered := len(keys)
+	for key := range to.Fields {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	if enc.keyLess != nil {
+		rest := keys[numOrdered:]
+		sort.Slice(rest, func(i, j int) bool {
+			return enc.keyLess(rest[i], rest[j])
+		})
+	}
+
+	for _, key := range keys {
+		if err := enc.writeUTF8(key); err != nil {
+			return err
+		}
+
+		if err := enc.encode(reflect.ValueOf(to.Fields[key])); err != nil {
+			return err
+		}
+	}
+
+	return enc.encodeObjectEnd()
 }
 
 func (enc *Encoder) writeU8(num uint8) error {
@@ -370,6 +707,10 @@ func (enc *Encoder) writeDouble(f64 float64) error {
 }
 
 func (enc *Encoder) writeUTF8(str string) error {
+	if len(str) > math.MaxUint16 {
+		return fmt.Errorf("too long string: Expected <= %d, Actual = %d", math.MaxUint16, len(str))
+	}
+
 	l := uint16(len(str))
 	if err := enc.writeU16(l); err != nil {
 		return err
@@ -377,3 +718,12 @@ func (enc *Encoder) writeUTF8(str string) error {
 	_, err := enc.w.Write([]byte(str))
 	return err
 }
+
+func (enc *Encoder) writeUTF8Long(str string) error {
+	l := uint32(len(str))
+	if err := enc.writeU32(l); err != nil {
+		return err
+	}
+	_, err := enc.w.Write([]byte(str))
+	return err
+}
@@ -9,13 +9,16 @@ package amf0
 
 import (
 	"bytes"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
 func TestEncodeCommon(t *testing.T) {
-	allTestCases := append(append([]testCase{}, testCases...), ptrNestedNumberTest, objectTest)
+	allTestCases := append(append([]testCase{}, testCases...), ptrNestedNumberTest, objectTest, typedObjectTest, unregisteredTypedObjectTest, taggedObjectTest, embeddedObjectTest, marshalerObjectTest, textMapTest, binaryTest, orderedObjectTest, orderedECMAArrayTest, avmPlusObjectTest)
 
 	for _, tc := range allTestCases {
 		tc := tc // capture
@@ -24,8 +27,7 @@ func TestEncodeCommon(t *testing.T) {
 			t.Parallel()
 
 			buf := bytes.NewBuffer([]byte{})
-			enc := NewEncoder(buf)
-			enc.sortKeys = true // for debuging
+			enc := NewEncoder(buf, WithSortedKeys()) // for debuging
 
 			err := enc.Encode(tc.Value)
 			require.Nil(t, err)
@@ -50,3 +52,408 @@ func TestEncodeUnsupportedTypes(t *testing.T) {
 	err := enc.Encode(ch)
 	require.Error(t, err)
 }
+
+func TestEncodeStringBoundary(t *testing.T) {
+	t.Run("65535 bytes is String", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(strings.Repeat("a", 65535))
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x02, 0xff, 0xff}, buf.Bytes()[:3])
+		require.Equal(t, 1+2+65535, buf.Len())
+	})
+
+	t.Run("65536 bytes is LongString", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(strings.Repeat("a", 65536))
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x0c, 0x00, 0x01, 0x00, 0x00}, buf.Bytes()[:5])
+		require.Equal(t, 1+4+65536, buf.Len())
+	})
+
+	t.Run("too long key", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(map[string]interface{}{
+			strings.Repeat("a", 65536): 1,
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestEncodeReference(t *testing.T) {
+	t.Run("shared pointers", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithReferences())
+
+		err := enc.Encode(referenceTest.Value)
+		require.Nil(t, err)
+		require.Equal(t, referenceTest.Binary, buf.Bytes())
+	})
+
+	t.Run("copied without references mode", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(referenceTest.Value)
+		require.Nil(t, err)
+		require.NotContains(t, buf.Bytes(), byte(MarkerReference))
+	})
+
+	t.Run("cyclic map", func(t *testing.T) {
+		m := map[string]interface{}{}
+		m["self"] = m
+
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithReferences())
+
+		err := enc.Encode(m)
+		require.Nil(t, err)
+		require.Equal(t, []byte{
+			0x03,       // Object Marker
+			0x00, 0x04, // - Length(4: u16) BigEndian
+			0x73, 0x65, 0x6c, 0x66, //   Key(self: []byte)
+			0x07,       //   - Reference Marker
+			0x00, 0x00, //     Index(0: u16) BigEndian
+			0x00, 0x00, // - Length(0: u16) BigEndian
+			0x09, //   - ObjectEndMarker
+		}, buf.Bytes())
+	})
+
+	t.Run("references are cleared by reset", func(t *testing.T) {
+		m := map[string]interface{}{}
+
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithReferences())
+
+		err := enc.Encode(m)
+		require.Nil(t, err)
+
+		buf.Reset()
+		enc.Reset(buf)
+
+		err = enc.Encode(m)
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x03, 0x00, 0x00, 0x09}, buf.Bytes())
+	})
+}
+
+func TestEncodeDateTimeZone(t *testing.T) {
+	jst := time.Unix(0x1234, 0).In(time.FixedZone("JST", 9*60*60))
+
+	t.Run("with time zone", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithEncodeTimeZone())
+
+		err := enc.Encode(jst)
+		require.Nil(t, err)
+		require.Equal(t, dateWithTimeZoneBinary, buf.Bytes())
+	})
+
+	t.Run("without time zone", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(jst)
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x00, 0x00}, buf.Bytes()[9:])
+	})
+
+	t.Run("raw date", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(Date{Millis: 0x1234 * 1000, TZ: 540})
+		require.Nil(t, err)
+		require.Equal(t, dateWithTimeZoneBinary, buf.Bytes())
+	})
+}
+
+func TestEncodeTimePointer(t *testing.T) {
+	tm := time.Unix(1, 0).In(time.UTC)
+
+	want := bytes.NewBuffer([]byte{})
+	err := NewEncoder(want).Encode(tm)
+	require.Nil(t, err)
+	require.Equal(t, byte(MarkerDate), want.Bytes()[0])
+
+	t.Run("pointer", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(&tm)
+		require.Nil(t, err)
+		require.Equal(t, want.Bytes(), buf.Bytes())
+	})
+
+	t.Run("field", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(struct {
+			T *time.Time `amf0:"t"`
+		}{T: &tm})
+		require.Nil(t, err)
+
+		expected := []byte{
+			0x03,             // Object Marker
+			0x00, 0x01, 0x74, // Key(t)
+		}
+		expected = append(expected, want.Bytes()...)
+		expected = append(expected, 0x00, 0x00, 0x09) // End
+		require.Equal(t, expected, buf.Bytes())
+	})
+}
+
+func TestEncodeDateRounding(t *testing.T) {
+	base := time.Unix(0x1234, 0)
+
+	testCases := []struct {
+		Name     string
+		Rounding DateRounding
+		Time     time.Time
+		Millis   float64
+		Error    bool
+	}{
+		{Name: "reject: milliseconds", Rounding: DateRoundingReject, Time: base.Add(123 * time.Millisecond), Millis: 0x1234*1000 + 123},
+		{Name: "reject: sub-milliseconds", Rounding: DateRoundingReject, Time: base.Add(1500 * time.Microsecond), Error: true},
+		{Name: "truncate", Rounding: DateRoundingTruncate, Time: base.Add(1999 * time.Microsecond), Millis: 0x1234*1000 + 1},
+		{Name: "truncate: negative", Rounding: DateRoundingTruncate, Time: time.Unix(0, -1500*int64(time.Microsecond)), Millis: -2},
+		{Name: "round: down", Rounding: DateRoundingRound, Time: base.Add(1499 * time.Microsecond), Millis: 0x1234*1000 + 1},
+		{Name: "round: half up", Rounding: DateRoundingRound, Time: base.Add(1500 * time.Microsecond), Millis: 0x1234*1000 + 2},
+		{Name: "round: negative", Rounding: DateRoundingRound, Time: time.Unix(0, -1500*int64(time.Microsecond)), Millis: -1},
+		{Name: "fraction", Rounding: DateRoundingFraction, Time: base.Add(1500 * time.Microsecond), Millis: 0x1234*1000 + 1.5},
+		{Name: "fraction: negative", Rounding: DateRoundingFraction, Time: time.Unix(0, -1500*int64(time.Microsecond)), Millis: -1.5},
+		{Name: "unknown", Rounding: DateRounding(42), Time: base, Error: true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBuffer([]byte{})
+			enc := NewEncoder(buf, WithDateRounding(tc.Rounding))
+
+			err := enc.Encode(tc.Time)
+			if tc.Error {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, dateBinary(tc.Millis, 0), buf.Bytes())
+		})
+	}
+}
+
+func TestEncodeKeyOrder(t *testing.T) {
+	m := ECMAArray{
+		"b":             1,
+		"a":             2,
+		"transactionId": 3,
+		"cmd":           4,
+	}
+
+	testCases := []struct {
+		Name   string
+		Option EncoderOption
+		Keys   []string
+	}{
+		{Name: "sorted", Option: WithSortedKeys(), Keys: []string{"a", "b", "cmd", "transactionId"}},
+		{Name: "priority", Option: WithKeyPriority("cmd", "transactionId"), Keys: []string{"cmd", "transactionId", "a", "b"}},
+		{Name: "comparator", Option: WithKeyLess(func(a, b string) bool { return a > b }), Keys: []string{"transactionId", "cmd", "b", "a"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			var expected []byte
+			for i := 0; i < 10; i++ {
+				buf := bytes.NewBuffer([]byte{})
+				enc := NewEncoder(buf, tc.Option)
+
+				err := enc.Encode(m)
+				require.Nil(t, err)
+				if expected == nil {
+					expected = buf.Bytes()
+				}
+				require.Equal(t, expected, buf.Bytes()) // stable
+			}
+
+			var keys []string
+			pos := 1 + 4 // Marker, Associative count
+			for {
+				l := int(expected[pos])<<8 | int(expected[pos+1])
+				if l == 0 {
+					break
+				}
+				keys = append(keys, string(expected[pos+2:pos+2+l]))
+				pos += 2 + l + 9 // Key, Number
+			}
+			require.Equal(t, tc.Keys, keys)
+		})
+	}
+}
+
+func TestEncodeTaggedObject(t *testing.T) {
+	t.Run("skipped and unexported fields are not encoded", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(sampleTaggedObject{
+			Name:    "a",
+			Skipped: "s",
+			Dash:    "d",
+			Count:   42,
+			hidden:  "h",
+		})
+		require.Nil(t, err)
+		require.Equal(t, taggedObjectTest.Binary, buf.Bytes())
+	})
+
+	t.Run("non-empty value is not omitted", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(sampleTaggedObject{
+			Omitted: "o",
+		})
+		require.Nil(t, err)
+		require.Contains(t, buf.String(), "omitted")
+	})
+}
+
+func TestEncodeEmbeddedObject(t *testing.T) {
+	type embeddedPtr struct {
+		*sampleBase
+		Name string `amf0:"name"`
+	}
+
+	t.Run("embedded pointer", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(embeddedPtr{
+			sampleBase: &sampleBase{
+				TransactionID: 1,
+			},
+			Name: "a",
+		})
+		require.Nil(t, err)
+		require.Equal(t, embeddedObjectTest.Binary, buf.Bytes())
+	})
+
+	t.Run("nil embedded pointer", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(embeddedPtr{
+			Name: "a",
+		})
+		require.Nil(t, err)
+		require.NotContains(t, buf.String(), "transactionId")
+	})
+}
+
+func TestEncodeMarshaler(t *testing.T) {
+	t.Run("value receiver", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(sampleStreamKey("k"))
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x02, 0x00, 0x05, 0x6b, 0x65, 0x79, 0x3a, 0x6b}, buf.Bytes())
+	})
+
+	t.Run("pointer receiver of non-addressable value", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(sampleFlags(5))
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, buf.Bytes())
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode((*sampleFlags)(nil))
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x05}, buf.Bytes())
+	})
+}
+
+func TestEncodeTextMarshaler(t *testing.T) {
+	t.Run("net.IP", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(net.IPv4(127, 0, 0, 1))
+		require.Nil(t, err)
+		require.Equal(t, append([]byte{0x02, 0x00, 0x09}, "127.0.0.1"...), buf.Bytes())
+	})
+
+	t.Run("time.Time is encoded as Date", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(time.Unix(0x1234, 0))
+		require.Nil(t, err)
+		require.Equal(t, byte(MarkerDate), buf.Bytes()[0])
+	})
+
+	t.Run("error from MarshalText", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(sampleEnum(42))
+		require.Error(t, err)
+	})
+
+	t.Run("map which has keys which do not implement TextMarshaler", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(map[int]int{1: 1})
+		require.IsType(t, &UnexpectedKeyTypeError{}, err)
+	})
+}
+
+func TestEncodeOrderedObject(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		var v OrderedObject
+		err := enc.Encode(v)
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x05}, buf.Bytes())
+	})
+
+	t.Run("reference", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithReferences())
+
+		o := OrderedObject{{Key: "a", Value: 1}}
+		err := enc.Encode([]interface{}{o, o})
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x07, 0x00, 0x01}, buf.Bytes()[buf.Len()-3:])
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode(OrderedObject{{Key: "", Value: 1}})
+		require.Error(t, err)
+	})
+}
@@ -9,7 +9,8 @@ package amf0
 
 import (
 	"fmt"
-	"reflect"
+
+	"github.com/yutopp/go-amf0/internal/shared"
 )
 
 // UnexpectedMarkerError Occurs when an unexpected marker is passed to the decoder
@@ -32,52 +33,35 @@ func (e *UnsupportedError) Error() string {
 }
 
 // UnexpectedValueError Occurs when an unexpected value is passed to the encoder
-type UnexpectedValueError struct {
-	Kind reflect.Kind
-}
-
-// Error Returns a string representation of the error
-func (e *UnexpectedValueError) Error() string {
-	return fmt.Sprintf("Unexpected value: Kind = %+v", e.Kind)
-}
+type UnexpectedValueError = shared.UnexpectedValueError
 
 // UnexpectedKeyTypeError Occurs when an unexpected key type is passed to the encoder/decoder
-type UnexpectedKeyTypeError struct {
-	ActualKind reflect.Kind
-	ExpectKind reflect.Kind
-}
-
-// Error Returns a string representation of the error
-func (e *UnexpectedKeyTypeError) Error() string {
-	return fmt.Sprintf("Unsupported key kind: %+v should be %+v", e.ActualKind.String(), e.ExpectKind.String())
-}
+type UnexpectedKeyTypeError = shared.UnexpectedKeyTypeError
 
 // DecodeError Occurs when general errors are happen in the decoder
-type DecodeError struct {
-	Message string
-	Dump    string
-}
-
-// Error Returns a string representation of the error
-func (e *DecodeError) Error() string {
-	return fmt.Sprintf("Message = %s, Dump = \n%s", e.Message, e.Dump)
-}
+type DecodeError = shared.DecodeError
 
 // NotAssignableError Occurs when failed to assign a decoded value to the receiver value
-type NotAssignableError struct {
+type NotAssignableError = shared.NotAssignableError
+
+// InvalidDateError Occurs when a Date cannot be represented as time.Time, such as NaN, Infinity or out of range values
+type InvalidDateError = shared.InvalidDateError
+
+// StateError Occurs when token-level APIs are called in an unexpected state
+type StateError struct {
 	Message string
-	Kind    reflect.Kind
-	Type    reflect.Type
 }
 
 // Error Returns a string representation of the error
-func (e *NotAssignableError) Error() string {
-	return fmt.Sprintf("Not assignable to receiver value: Message=%+v, Kind=%s, Type=%s",
-		e.Message,
-		e.Kind.String(),
-		e.Type.String(),
-	)
+func (e *StateError) Error() string {
+	return fmt.Sprintf("Unexpected state: Message = %s", e.Message)
 }
 
+// LimitExceededError Occurs when a decoded value exceeds limits specified by DecoderOptions
+type LimitExceededError = shared.LimitExceededError
+
+// ErrNoAMF3Codec Occurs when AMF3 values are encoded without AMF3 codecs
+var ErrNoAMF3Codec = fmt.Errorf("No AMF3 codec")
+
 // ErrObjectEndMarker ...
 var ErrObjectEndMarker = fmt.Errorf("ObjectEndMarker")
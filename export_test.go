@@ -8,6 +8,11 @@
 package amf0
 
 import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -70,6 +75,26 @@ var testCases = []testCase{
 			0x05,
 		},
 	},
+	{
+		Name:  "XMLDocument",
+		Value: XMLDocument("<a>b</a>"),
+		Binary: []byte{
+			// XMLDocument Marker
+			0x0f,
+			// Length(8: u32) BigEndian
+			0x00, 0x00, 0x00, 0x08,
+			// Value(<a>b</a>: []byte)
+			0x3c, 0x61, 0x3e, 0x62, 0x3c, 0x2f, 0x61, 0x3e,
+		},
+	},
+	{
+		Name:  "Undefined",
+		Value: Undefined,
+		Binary: []byte{
+			// Undefined Marker
+			0x06,
+		},
+	},
 	{
 		Name:  "Nil Map",
 		Value: (map[string]interface{})(nil),
@@ -257,3 +282,578 @@ var objectTest = testCase{
 		0x09,
 	},
 }
+
+type sampleSharedObject struct {
+	X *sampleObject `amf0:"x"`
+	Y *sampleObject `amf0:"y"`
+}
+
+var referenceTest = testCase{
+	Name: "Reference",
+	Value: func() sampleSharedObject {
+		o := &sampleObject{A: "s", B: 42}
+		return sampleSharedObject{X: o, Y: o}
+	}(),
+	Binary: []byte{
+		// Object Marker (reference index 0)
+		0x03,
+		// - Length(1: u16) BigEndian
+		0x00, 0x01,
+		//   Key(x: []byte)
+		0x78,
+		//   - Object Marker (reference index 1)
+		0x03,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//       Key(a: []byte)
+		0x61,
+		//       - String Marker
+		0x02,
+		//         Length(1: u16) BigEndian
+		0x00, 0x01,
+		//         Value(s: []byte)
+		0x73,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//       Key(b: []byte)
+		0x62,
+		//       - Number Marker
+		0x00,
+		//         Value(42: double) BigEndian
+		0x40, 0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		//     Length(0: u16) BigEndian
+		0x00, 0x00,
+		//       - ObjectEndMarker
+		0x09,
+		// - Length(1: u16) BigEndian
+		0x00, 0x01,
+		//   Key(y: []byte)
+		0x79,
+		//   - Reference Marker
+		0x07,
+		//     Index(1: u16) BigEndian
+		0x00, 0x01,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+type sampleUser struct {
+	Name string `amf0:"name"`
+	Age  int    `amf0:"age"`
+}
+
+func init() {
+	RegisterType("com.example.User", sampleUser{})
+}
+
+var typedObjectTest = testCase{
+	Name: "Typed Object",
+	Value: sampleUser{
+		Name: "a",
+		Age:  20,
+	},
+	Binary: []byte{
+		// TypedObject Marker
+		0x10,
+		// - Length(16: u16) BigEndian
+		0x00, 0x10,
+		//   ClassName(com.example.User: []byte)
+		0x63, 0x6f, 0x6d, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x55, 0x73, 0x65, 0x72,
+		// - Length(4: u16) BigEndian
+		0x00, 0x04,
+		//   Key(name: []byte)
+		0x6e, 0x61, 0x6d, 0x65,
+		//   - String Marker
+		0x02,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//     Value(a: []byte)
+		0x61,
+		// - Length(3: u16) BigEndian
+		0x00, 0x03,
+		//   Key(age: []byte)
+		0x61, 0x67, 0x65,
+		//   - Number Marker
+		0x00,
+		//     Value(20: double) BigEndian
+		0x40, 0x34, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+var unregisteredTypedObjectTest = testCase{
+	Name: "Typed Object (unregistered)",
+	Value: TypedObject{
+		ClassName: "x.Y",
+		Fields: map[string]interface{}{
+			"b": &Value{Kind: MarkerString, String: "s"},
+			"a": &Value{Kind: MarkerNumber, Number: 42},
+		},
+		keys: []string{"b", "a"},
+	},
+	Binary: []byte{
+		// TypedObject Marker
+		0x10,
+		// - Length(3: u16) BigEndian
+		0x00, 0x03,
+		//   ClassName(x.Y: []byte)
+		0x78, 0x2e, 0x59,
+		// - Length(1: u16) BigEndian
+		0x00, 0x01,
+		//   Key(b: []byte)
+		0x62,
+		//   - String Marker
+		0x02,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//     Value(s: []byte)
+		0x73,
+		// - Length(1: u16) BigEndian
+		0x00, 0x01,
+		//   Key(a: []byte)
+		0x61,
+		//   - Number Marker
+		0x00,
+		//     Value(42: double) BigEndian
+		0x40, 0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+var dateWithTimeZoneBinary = []byte{
+	// Date Marker
+	0x0b,
+	// Unix time[ms]
+	0x41, 0x51, 0xc6, 0xc8, 0x00, 0x00, 0x00, 0x00,
+	// Time zone(540: s16) BigEndian
+	0x02, 0x1c,
+}
+
+func dateBinary(unixMs float64, tz int16) []byte {
+	bin := make([]byte, 11)
+	bin[0] = byte(MarkerDate)
+	binary.BigEndian.PutUint64(bin[1:], math.Float64bits(unixMs))
+	binary.BigEndian.PutUint16(bin[9:], uint16(tz))
+
+	return bin
+}
+
+type sampleTaggedObject struct {
+	Name    string `amf0:"name"`
+	Omitted string `amf0:"omitted,omitempty"`
+	Skipped string `amf0:"-"`
+	Dash    string `amf0:"-,"`
+	Count   int    `amf0:",string"`
+	hidden  string
+}
+
+var taggedObjectTest = testCase{
+	Name: "Tagged Object",
+	Value: sampleTaggedObject{
+		Name:  "a",
+		Dash:  "d",
+		Count: 42,
+	},
+	Binary: []byte{
+		// Object Marker
+		0x03,
+		// - Length(4: u16) BigEndian
+		0x00, 0x04,
+		//   Key(name: []byte)
+		0x6e, 0x61, 0x6d, 0x65,
+		//   - String Marker
+		0x02,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//     Value(a: []byte)
+		0x61,
+		// - Length(1: u16) BigEndian
+		0x00, 0x01,
+		//   Key(-: []byte)
+		0x2d,
+		//   - String Marker
+		0x02,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//     Value(d: []byte)
+		0x64,
+		// - Length(5: u16) BigEndian
+		0x00, 0x05,
+		//   Key(Count: []byte)
+		0x43, 0x6f, 0x75, 0x6e, 0x74,
+		//   - String Marker
+		0x02,
+		//     Length(2: u16) BigEndian
+		0x00, 0x02,
+		//     Value(42: []byte)
+		0x34, 0x32,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+type sampleBase struct {
+	TransactionID int `amf0:"transactionId"`
+}
+
+type sampleEmbeddedObject struct {
+	sampleBase
+	Name string `amf0:"name"`
+}
+
+var embeddedObjectTest = testCase{
+	Name: "Embedded Object",
+	Value: sampleEmbeddedObject{
+		sampleBase: sampleBase{
+			TransactionID: 1,
+		},
+		Name: "a",
+	},
+	Binary: []byte{
+		// Object Marker
+		0x03,
+		// - Length(13: u16) BigEndian
+		0x00, 0x0d,
+		//   Key(transactionId: []byte)
+		0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64,
+		//   - Number Marker
+		0x00,
+		//     Value(1: double) BigEndian
+		0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// - Length(4: u16) BigEndian
+		0x00, 0x04,
+		//   Key(name: []byte)
+		0x6e, 0x61, 0x6d, 0x65,
+		//   - String Marker
+		0x02,
+		//     Length(1: u16) BigEndian
+		0x00, 0x01,
+		//     Value(a: []byte)
+		0x61,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+// sampleStreamKey Encoded as a String which has a prefix
+type sampleStreamKey string
+
+func (k sampleStreamKey) MarshalAMF0(enc *Encoder) error {
+	return enc.Encode("key:" + string(k))
+}
+
+func (k *sampleStreamKey) UnmarshalAMF0(dec *Decoder) error {
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		return err
+	}
+
+	*k = sampleStreamKey(strings.TrimPrefix(s, "key:"))
+	return nil
+}
+
+// sampleFlags Encoded as a Number by raw bytes
+type sampleFlags uint32
+
+func (f *sampleFlags) MarshalAMF0(enc *Encoder) error {
+	bin := make([]byte, 9)
+	bin[0] = byte(MarkerNumber)
+	binary.BigEndian.PutUint64(bin[1:], math.Float64bits(float64(*f)))
+
+	_, err := enc.Write(bin)
+	return err
+}
+
+func (f *sampleFlags) UnmarshalAMF0(dec *Decoder) error {
+	bin := make([]byte, 9)
+	if _, err := io.ReadFull(dec, bin); err != nil {
+		return err
+	}
+	if Marker(bin[0]) != MarkerNumber {
+		return &UnexpectedMarkerError{Marker: bin[0]}
+	}
+
+	*f = sampleFlags(math.Float64frombits(binary.BigEndian.Uint64(bin[1:])))
+	return nil
+}
+
+type sampleMarshalerObject struct {
+	Key   sampleStreamKey  `amf0:"key"`
+	Flags sampleFlags      `amf0:"flags"`
+	Ptr   *sampleStreamKey `amf0:"ptr"`
+}
+
+var marshalerObjectTest = testCase{
+	Name: "Marshaler Object",
+	Value: sampleMarshalerObject{
+		Key:   "k",
+		Flags: 5,
+	},
+	Binary: []byte{
+		// Object Marker
+		0x03,
+		// - Length(3: u16) BigEndian
+		0x00, 0x03,
+		//   Key(key: []byte)
+		0x6b, 0x65, 0x79,
+		//   - String Marker
+		0x02,
+		//     Length(5: u16) BigEndian
+		0x00, 0x05,
+		//     Value(key:k: []byte)
+		0x6b, 0x65, 0x79, 0x3a, 0x6b,
+		// - Length(5: u16) BigEndian
+		0x00, 0x05,
+		//   Key(flags: []byte)
+		0x66, 0x6c, 0x61, 0x67, 0x73,
+		//   - Number Marker
+		0x00,
+		//     Value(5: double) BigEndian
+		0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// - Length(3: u16) BigEndian
+		0x00, 0x03,
+		//   Key(ptr: []byte)
+		0x70, 0x74, 0x72,
+		//   - Null Marker
+		0x05,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+// sampleEnum Encoded as a text
+type sampleEnum int
+
+var sampleEnumNames = []string{"zero", "one"}
+
+func (e sampleEnum) MarshalText() ([]byte, error) {
+	if int(e) >= len(sampleEnumNames) {
+		return nil, fmt.Errorf("unknown enum: %d", e)
+	}
+	return []byte(sampleEnumNames[e]), nil
+}
+
+func (e *sampleEnum) UnmarshalText(text []byte) error {
+	for i, name := range sampleEnumNames {
+		if name == string(text) {
+			*e = sampleEnum(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown enum: %s", text)
+}
+
+// sampleBinary Encoded as a binary
+type sampleBinary struct {
+	A, B byte
+}
+
+func (b sampleBinary) MarshalBinary() ([]byte, error) {
+	return []byte{b.A, b.B}, nil
+}
+
+func (b *sampleBinary) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return fmt.Errorf("unexpected length: %d", len(data))
+	}
+	b.A, b.B = data[0], data[1]
+	return nil
+}
+
+var textMapTest = testCase{
+	Name: "Map which has TextMarshaler keys",
+	Value: map[sampleEnum]sampleEnum{
+		0: 1,
+	},
+	Binary: []byte{
+		// Object Marker
+		0x03,
+		// - Length(4: u16) BigEndian
+		0x00, 0x04,
+		//   Key(zero: []byte)
+		0x7a, 0x65, 0x72, 0x6f,
+		//   - String Marker
+		0x02,
+		//     Length(3: u16) BigEndian
+		0x00, 0x03,
+		//     Value(one: []byte)
+		0x6f, 0x6e, 0x65,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+var binaryTest = testCase{
+	Name:  "BinaryMarshaler",
+	Value: sampleBinary{A: 1, B: 2},
+	Binary: []byte{
+		// Strict Array Marker
+		0x0a,
+		// Array length (2: u32) BigEndian
+		0x00, 0x00, 0x00, 0x02,
+		// Elem 0 (number)
+		0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// Elem 1 (number)
+		0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+}
+
+var orderedObjectTest = testCase{
+	Name: "OrderedObject",
+	Value: OrderedObject{
+		{Key: "app", Value: "live"},
+		{Key: "tcUrl", Value: "rtmp://x"},
+		{Key: "fpad", Value: false},
+	},
+	Binary: []byte{
+		// Object Marker
+		0x03,
+		// - Length(3: u16) BigEndian
+		0x00, 0x03,
+		//   Key(app: []byte)
+		0x61, 0x70, 0x70,
+		//   - String Marker
+		0x02,
+		//     Length(4: u16) BigEndian
+		0x00, 0x04,
+		//     Value(live: []byte)
+		0x6c, 0x69, 0x76, 0x65,
+		// - Length(5: u16) BigEndian
+		0x00, 0x05,
+		//   Key(tcUrl: []byte)
+		0x74, 0x63, 0x55, 0x72, 0x6c,
+		//   - String Marker
+		0x02,
+		//     Length(8: u16) BigEndian
+		0x00, 0x08,
+		//     Value(rtmp://x: []byte)
+		0x72, 0x74, 0x6d, 0x70, 0x3a, 0x2f, 0x2f, 0x78,
+		// - Length(4: u16) BigEndian
+		0x00, 0x04,
+		//   Key(fpad: []byte)
+		0x66, 0x70, 0x61, 0x64,
+		//   - Boolean Marker
+		0x01,
+		//     Value(false: u8)
+		0x00,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+var orderedECMAArrayTest = testCase{
+	Name: "OrderedECMAArray",
+	Value: OrderedECMAArray{
+		{Key: "width", Value: float64(2)},
+		{Key: "height", Value: OrderedObject{}},
+	},
+	Binary: []byte{
+		// ECMA Array Marker
+		0x08,
+		// Associative count(2: u32) BigEndian
+		0x00, 0x00, 0x00, 0x02,
+		// - Length(5: u16) BigEndian
+		0x00, 0x05,
+		//   Key(width: []byte)
+		0x77, 0x69, 0x64, 0x74, 0x68,
+		//   - Number Marker
+		0x00,
+		//     Value(2: double) BigEndian
+		0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// - Length(6: u16) BigEndian
+		0x00, 0x06,
+		//   Key(height: []byte)
+		0x68, 0x65, 0x69, 0x67, 0x68, 0x74,
+		//   - Object Marker
+		0x03,
+		//     Length(0: u16) BigEndian
+		0x00, 0x00,
+		//       - ObjectEndMarker
+		0x09,
+		// - Length(0: u16) BigEndian
+		0x00, 0x00,
+		//   - ObjectEndMarker
+		0x09,
+	},
+}
+
+// avmPlusPayload An AMF3 array which has an associative member and two objects sharing traits
+var avmPlusPayload = []byte{
+	// AMF3 Array Marker
+	0x09,
+	//   U29A(dense count 2, inline)
+	0x05,
+	//   - Key(k: inline, length 1)
+	0x03, 0x6b,
+	//     Integer Marker, U29(127)
+	0x04, 0x7f,
+	//   - Key(empty)
+	0x01,
+	//   - AMF3 Object Marker
+	0x0a,
+	//     U29O-traits(1 sealed member, dynamic, inline)
+	0x1b,
+	//     Class name(empty)
+	0x01,
+	//     Member name(x)
+	0x03, 0x78,
+	//     - Double Marker, Value(1.5)
+	0x05, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	//     - Key(y)
+	0x03, 0x79,
+	//       String Marker, Value(hi)
+	0x06, 0x05, 0x68, 0x69,
+	//     - Key(empty)
+	0x01,
+	//   - AMF3 Object Marker
+	0x0a,
+	//     U29O-traits-ref(0)
+	0x01,
+	//     - True Marker
+	0x03,
+	//     - Key(empty)
+	0x01,
+}
+
+var avmPlusObjectTest = testCase{
+	Name:  "AVMPlusObject",
+	Value: []interface{}{float64(1), AVMPlusObject(avmPlusPayload), "end"},
+	Binary: append(append([]byte{
+		// Strict Array Marker
+		0x0a,
+		// Length(3: u32) BigEndian
+		0x00, 0x00, 0x00, 0x03,
+		// - Number Marker
+		0x00,
+		//   Value(1: double) BigEndian
+		0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// - AVMPlusObject Marker
+		0x11,
+	}, avmPlusPayload...), []byte{
+		// - String Marker
+		0x02,
+		//   Length(3: u16) BigEndian
+		0x00, 0x03,
+		//   Value(end: []byte)
+		0x65, 0x6e, 0x64,
+	}...),
+}
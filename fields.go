@@ -0,0 +1,45 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"reflect"
+
+	"github.com/yutopp/go-amf0/internal/structfields"
+)
+
+// tagName A name of struct tags which are used by this package. See structfields.Cached for the format.
+const tagName = "amf0"
+
+// cachedTypeFields Returns fields of the struct type resolved by `amf0` tags. Results are cached per type.
+func cachedTypeFields(ty reflect.Type) *structfields.Fields {
+	return structfields.Cached(ty, tagName)
+}
+
+// fieldByIndex Returns the field of the struct. It returns false if the field is in a nil embedded pointer.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	return structfields.ByIndex(rv, index)
+}
+
+// fieldByIndexAlloc Returns the field of the struct. Nil embedded pointers are allocated.
+func fieldByIndexAlloc(rv reflect.Value, index []int) (reflect.Value, error) {
+	fv, ok := structfields.ByIndexAlloc(rv, index)
+	if !ok {
+		return reflect.Value{}, &NotAssignableError{
+			Message: "Embedded pointer to unexported struct",
+			Kind:    fv.Kind(),
+			Type:    fv.Type(),
+		}
+	}
+
+	return fv, nil
+}
+
+func isEmptyValue(rv reflect.Value) bool {
+	return structfields.IsEmptyValue(rv)
+}
@@ -0,0 +1,121 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeFields(t *testing.T) {
+	namesOf := func(v interface{}) []string {
+		var names []string
+		for _, f := range cachedTypeFields(reflect.TypeOf(v)).List {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		require.Equal(t, []string{"name", "omitted", "-", "Count"}, namesOf(sampleTaggedObject{}))
+	})
+
+	t.Run("tagged field dominates", func(t *testing.T) {
+		type s struct {
+			A string
+			B string `amf0:"A"`
+		}
+		require.Equal(t, []string{"A"}, namesOf(s{}))
+		require.Equal(t, []int{1}, cachedTypeFields(reflect.TypeOf(s{})).ByName["A"].Index)
+	})
+
+	t.Run("conflicted fields are ignored", func(t *testing.T) {
+		type s struct {
+			A string `amf0:"x"`
+			B string `amf0:"x"`
+			C string
+		}
+		require.Equal(t, []string{"C"}, namesOf(s{}))
+	})
+
+	t.Run("string option is ignored for non-numeric types", func(t *testing.T) {
+		type s struct {
+			A string `amf0:",string"`
+			B bool   `amf0:",string"`
+		}
+		fs := cachedTypeFields(reflect.TypeOf(s{}))
+		require.False(t, fs.List[0].AsString)
+		require.True(t, fs.List[1].AsString)
+	})
+}
+
+func TestTypeFieldsEmbedded(t *testing.T) {
+	namesOf := func(v interface{}) []string {
+		var names []string
+		for _, f := range cachedTypeFields(reflect.TypeOf(v)).List {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	type A struct {
+		X int
+		Y int
+	}
+	type B struct {
+		X int
+		Z int
+	}
+
+	t.Run("flattened", func(t *testing.T) {
+		type s struct {
+			*A
+			W int
+		}
+		require.Equal(t, []string{"X", "Y", "W"}, namesOf(s{}))
+		require.Equal(t, []int{0, 1}, cachedTypeFields(reflect.TypeOf(s{})).ByName["Y"].Index)
+	})
+
+	t.Run("conflicted fields at the same depth are ignored", func(t *testing.T) {
+		type s struct {
+			A
+			B
+		}
+		require.Equal(t, []string{"Y", "Z"}, namesOf(s{}))
+	})
+
+	t.Run("shallower field dominates", func(t *testing.T) {
+		type s struct {
+			A
+			X string
+		}
+		require.Equal(t, []string{"Y", "X"}, namesOf(s{}))
+		require.Equal(t, []int{1}, cachedTypeFields(reflect.TypeOf(s{})).ByName["X"].Index)
+	})
+
+	t.Run("tagged field dominates at the same depth", func(t *testing.T) {
+		type C struct {
+			V int `amf0:"X"`
+		}
+		type s struct {
+			A
+			C
+		}
+		require.Equal(t, []string{"Y", "X"}, namesOf(s{}))
+		require.Equal(t, []int{1, 0}, cachedTypeFields(reflect.TypeOf(s{})).ByName["X"].Index)
+	})
+
+	t.Run("tagged anonymous struct is not flattened", func(t *testing.T) {
+		type s struct {
+			A `amf0:"a"`
+		}
+		require.Equal(t, []string{"a"}, namesOf(s{}))
+	})
+}
@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package flvscript
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+var onMetaData = &OnMetaData{
+	Duration:        float64Ptr(10),
+	Width:           float64Ptr(640),
+	Height:          float64Ptr(360),
+	FrameRate:       float64Ptr(30),
+	VideoCodecID:    float64Ptr(7),
+	AudioSampleRate: float64Ptr(44100),
+	AudioCodecID:    float64Ptr(10),
+	Stereo:          boolPtr(true),
+	Keyframes: &Keyframes{
+		Times:         []float64{0, 2},
+		FilePositions: []float64{100, 2000},
+	},
+	Extra: map[string]interface{}{
+		"encoder": "Lavf58",
+	},
+}
+
+var onMetaDataBinary = []byte{
+	0x02, 0x00, 0x0a, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, // String(onMetaData)
+	0x08, 0x00, 0x00, 0x00, 0x0a, // ECMA Array Marker, Count(10)
+	0x00, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, //   Key(duration)
+	0x00, 0x40, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(10)
+	0x00, 0x05, 0x77, 0x69, 0x64, 0x74, 0x68, //   Key(width)
+	0x00, 0x40, 0x84, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(640)
+	0x00, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, //   Key(height)
+	0x00, 0x40, 0x76, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(360)
+	0x00, 0x09, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x72, 0x61, 0x74, 0x65, //   Key(framerate)
+	0x00, 0x40, 0x3e, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(30)
+	0x00, 0x0c, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x69, 0x64, //   Key(videocodecid)
+	0x00, 0x40, 0x1c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(7)
+	0x00, 0x0f, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x72, 0x61, 0x74, 0x65, //   Key(audiosamplerate)
+	0x00, 0x40, 0xe5, 0x88, 0x80, 0x00, 0x00, 0x00, 0x00, //   Number(44100)
+	0x00, 0x0c, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x63, 0x6f, 0x64, 0x65, 0x63, 0x69, 0x64, //   Key(audiocodecid)
+	0x00, 0x40, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(10)
+	0x00, 0x06, 0x73, 0x74, 0x65, 0x72, 0x65, 0x6f, //   Key(stereo)
+	0x01, 0x01, //   Boolean(true)
+	0x00, 0x07, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x72, //   Key(encoder)
+	0x02, 0x00, 0x06, 0x4c, 0x61, 0x76, 0x66, 0x35, 0x38, //   String(Lavf58)
+	0x00, 0x09, 0x6b, 0x65, 0x79, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x73, //   Key(keyframes)
+	0x03,                                     //   Object Marker
+	0x00, 0x05, 0x74, 0x69, 0x6d, 0x65, 0x73, //     Key(times)
+	0x0a, 0x00, 0x00, 0x00, 0x02, //     Strict Array Marker, Length(2)
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //       Number(0)
+	0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //       Number(2)
+	0x00, 0x0d, 0x66, 0x69, 0x6c, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, //     Key(filepositions)
+	0x0a, 0x00, 0x00, 0x00, 0x02, //     Strict Array Marker, Length(2)
+	0x00, 0x40, 0x59, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //       Number(100)
+	0x00, 0x40, 0x9f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, //       Number(2000)
+	0x00, 0x00, 0x09, //     End
+	0x00, 0x00, 0x09, //   End
+}
@@ -0,0 +1,287 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package flvscript Readers and writers of bodies of FLV SCRIPTDATA tags
+package flvscript
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/yutopp/go-amf0"
+)
+
+// NameOnMetaData A name of onMetaData script data
+const NameOnMetaData = "onMetaData"
+
+// OnMetaData A typed representation of onMetaData script data
+// Fields which are nil are treated as absent. Properties which are not known are kept in Extra.
+type OnMetaData struct {
+	Duration        *float64 // in seconds
+	Width           *float64
+	Height          *float64
+	VideoDataRate   *float64 // in kilobits per second
+	FrameRate       *float64
+	VideoCodecID    *float64
+	AudioDataRate   *float64 // in kilobits per second
+	AudioSampleRate *float64
+	AudioSampleSize *float64
+	Stereo          *bool
+	AudioCodecID    *float64
+	FileSize        *float64 // in bytes
+	Keyframes       *Keyframes
+
+	Extra map[string]interface{}
+}
+
+// Keyframes Positions of keyframes which are used for seeking. Both slices must have the same length.
+type Keyframes struct {
+	Times         []float64 // in seconds
+	FilePositions []float64 // in bytes
+}
+
+type numberProperty struct {
+	key   string
+	value **float64
+}
+
+// numberProperties Returns numeric properties in the written order
+func (md *OnMetaData) numberProperties() []numberProperty {
+	return []numberProperty{
+		{"duration", &md.Duration},
+		{"width", &md.Width},
+		{"height", &md.Height},
+		{"videodatarate", &md.VideoDataRate},
+		{"framerate", &md.FrameRate},
+		{"videocodecid", &md.VideoCodecID},
+		{"audiodatarate", &md.AudioDataRate},
+		{"audiosamplerate", &md.AudioSampleRate},
+		{"audiosamplesize", &md.AudioSampleSize},
+		{"audiocodecid", &md.AudioCodecID},
+		{"filesize", &md.FileSize},
+	}
+}
+
+const (
+	keyStereo    = "stereo"
+	keyKeyframes = "keyframes"
+)
+
+// ReadOnMetaData Read a body of the SCRIPTDATA tag which is onMetaData.
+// Values are accepted as either an ECMA Array or an Object. Options are applied to the decoder, such as amf0.WithDecoderOptions to limit resources.
+func ReadOnMetaData(r io.Reader, opts ...amf0.DecoderOption) (*OnMetaData, error) {
+	dec := amf0.NewDecoder(r, opts...)
+
+	var name string
+	if err := dec.Decode(&name); err != nil {
+		return nil, err
+	}
+	if name != NameOnMetaData {
+		return nil, fmt.Errorf("not onMetaData: Name = %s", name)
+	}
+
+	var props map[string]interface{}
+	if err := dec.Decode(&props); err != nil {
+		return nil, err
+	}
+
+	md := &OnMetaData{}
+	numbers := md.numberProperties()
+
+PROPS:
+	for key, value := range props {
+		for _, p := range numbers {
+			if p.key != key {
+				continue
+			}
+			if n, ok := value.(float64); ok {
+				*p.value = &n
+				continue PROPS
+			}
+		}
+
+		switch key {
+		case keyStereo:
+			if b, ok := value.(bool); ok {
+				md.Stereo = &b
+				continue
+			}
+
+		case keyKeyframes:
+			if kf, ok := keyframesOf(value); ok {
+				md.Keyframes = kf
+				continue
+			}
+		}
+
+		// Unknown keys or values which have unexpected types
+		if md.Extra == nil {
+			md.Extra = make(map[string]interface{})
+		}
+		md.Extra[key] = value
+	}
+
+	return md, nil
+}
+
+func keyframesOf(value interface{}) (*Keyframes, bool) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	times, ok := float64sOf(obj["times"])
+	if !ok {
+		return nil, false
+	}
+	positions, ok := float64sOf(obj["filepositions"])
+	if !ok {
+		return nil, false
+	}
+	if len(times) != len(positions) || len(obj) != 2 {
+		return nil, false
+	}
+
+	return &Keyframes{
+		Times:         times,
+		FilePositions: positions,
+	}, true
+}
+
+func float64sOf(value interface{}) ([]float64, bool) {
+	arr, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	nums := make([]float64, len(arr))
+	for i, v := range arr {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, false
+		}
+		nums[i] = n
+	}
+
+	return nums, true
+}
+
+// WriteOnMetaData Write a body of the SCRIPTDATA tag which is onMetaData.
+// Values are written as an ECMA Array in the order of known properties, extra properties sorted by keys and keyframes.
+func WriteOnMetaData(w io.Writer, md *OnMetaData) error {
+	var numbers []numberProperty
+	populated := make(map[string]bool)
+	for _, p := range md.numberProperties() {
+		if *p.value != nil {
+			numbers = append(numbers, p)
+			populated[p.key] = true
+		}
+	}
+	populated[keyStereo] = md.Stereo != nil
+	populated[keyKeyframes] = md.Keyframes != nil
+
+	extraKeys := make([]string, 0, len(md.Extra))
+	for key := range md.Extra {
+		if populated[key] {
+			continue // Typed fields have priority
+		}
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+
+	if kf := md.Keyframes; kf != nil && len(kf.Times) != len(kf.FilePositions) {
+		return fmt.Errorf("lengths of keyframes are different: Times = %d, FilePositions = %d", len(kf.Times), len(kf.FilePositions))
+	}
+
+	count := len(numbers) + len(extraKeys)
+	if md.Stereo != nil {
+		count++
+	}
+	if md.Keyframes != nil {
+		count++
+	}
+
+	enc := amf0.NewEncoder(w, amf0.WithSortedKeys())
+	if err := enc.WriteString(NameOnMetaData); err != nil {
+		return err
+	}
+
+	if err := enc.BeginECMAArray(uint32(count)); err != nil {
+		return err
+	}
+
+	for _, p := range numbers {
+		if err := writeProperty(enc, p.key, **p.value); err != nil {
+			return err
+		}
+	}
+
+	if md.Stereo != nil {
+		if err := writeProperty(enc, keyStereo, *md.Stereo); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range extraKeys {
+		if err := writeProperty(enc, key, md.Extra[key]); err != nil {
+			return err
+		}
+	}
+
+	if md.Keyframes != nil {
+		if err := enc.WriteKey(keyKeyframes); err != nil {
+			return err
+		}
+		if err := writeKeyframes(enc, md.Keyframes); err != nil {
+			return err
+		}
+	}
+
+	return enc.EndObject()
+}
+
+func writeProperty(enc *amf0.Encoder, key string, value interface{}) error {
+	if err := enc.WriteKey(key); err != nil {
+		return err
+	}
+
+	return enc.Encode(value)
+}
+
+// writeKeyframes Writes keyframes element by element not to build large values
+func writeKeyframes(enc *amf0.Encoder, kf *Keyframes) error {
+	if err := enc.BeginObject(); err != nil {
+		return err
+	}
+
+	for _, p := range []struct {
+		key    string
+		values []float64
+	}{
+		{"times", kf.Times},
+		{"filepositions", kf.FilePositions},
+	} {
+		if err := enc.WriteKey(p.key); err != nil {
+			return err
+		}
+
+		if err := enc.BeginStrictArray(uint32(len(p.values))); err != nil {
+			return err
+		}
+		for _, v := range p.values {
+			if err := enc.WriteNumber(v); err != nil {
+				return err
+			}
+		}
+		if err := enc.EndArray(); err != nil {
+			return err
+		}
+	}
+
+	return enc.EndObject()
+}
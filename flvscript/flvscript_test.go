@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package flvscript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func TestReadOnMetaData(t *testing.T) {
+	t.Run("ECMA Array", func(t *testing.T) {
+		r := bytes.NewReader(onMetaDataBinary)
+
+		md, err := ReadOnMetaData(r)
+		require.Nil(t, err)
+		require.Equal(t, onMetaData, md)
+		require.Equal(t, 0, r.Len())
+	})
+
+	t.Run("Object", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := amf0.NewEncoder(buf)
+		require.Nil(t, enc.Encode(NameOnMetaData))
+		require.Nil(t, enc.Encode(map[string]interface{}{
+			"duration": 1.5,
+			"stereo":   false,
+		}))
+
+		md, err := ReadOnMetaData(buf)
+		require.Nil(t, err)
+		require.Equal(t, &OnMetaData{Duration: float64Ptr(1.5), Stereo: boolPtr(false)}, md)
+	})
+
+	t.Run("unexpected types", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := amf0.NewEncoder(buf)
+		require.Nil(t, enc.Encode(NameOnMetaData))
+		require.Nil(t, enc.Encode(amf0.ECMAArray{
+			"width":     "640",
+			"keyframes": map[string]interface{}{"times": []interface{}{float64(0)}},
+		}))
+
+		md, err := ReadOnMetaData(buf)
+		require.Nil(t, err)
+		require.Equal(t, &OnMetaData{
+			Extra: map[string]interface{}{
+				"width":     "640",
+				"keyframes": map[string]interface{}{"times": []interface{}{float64(0)}},
+			},
+		}, md)
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		bin := []byte{
+			0x02, 0x00, 0x0a, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x61, 0x44, 0x61, 0x74, 0x61, // String(onMetaData)
+			0x08, 0x00, 0x00, 0x00, 0x01, // ECMA Array Marker, Count(1)
+			0x00, 0x01, 0x61, //            Key(a)
+			0x0a, 0x00, 0xff, 0xff, 0xff, // Strict Array(length 16777215) without elements
+		}
+
+		_, err := ReadOnMetaData(bytes.NewReader(bin), amf0.WithDecoderOptions(amf0.DecoderOptions{MaxBytes: 64}))
+		require.IsType(t, &amf0.LimitExceededError{}, err)
+		require.Equal(t, "MaxBytes", err.(*amf0.LimitExceededError).Limit)
+	})
+
+	t.Run("not onMetaData", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := amf0.NewEncoder(buf)
+		require.Nil(t, enc.Encode("onCuePoint"))
+		require.Nil(t, enc.Encode(amf0.ECMAArray{}))
+
+		_, err := ReadOnMetaData(buf)
+		require.Error(t, err)
+	})
+}
+
+func TestWriteOnMetaData(t *testing.T) {
+	t.Run("ECMA Array", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+
+		err := WriteOnMetaData(buf, onMetaData)
+		require.Nil(t, err)
+		require.Equal(t, onMetaDataBinary, buf.Bytes())
+	})
+
+	t.Run("typed fields have priority", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+
+		err := WriteOnMetaData(buf, &OnMetaData{
+			Width: float64Ptr(640),
+			Extra: map[string]interface{}{
+				"width": "1280",
+			},
+		})
+		require.Nil(t, err)
+
+		md, err := ReadOnMetaData(buf)
+		require.Nil(t, err)
+		require.Equal(t, &OnMetaData{Width: float64Ptr(640)}, md)
+	})
+
+	t.Run("zeros and unexpected types are kept", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := amf0.NewEncoder(buf)
+		require.Nil(t, enc.Encode(NameOnMetaData))
+		require.Nil(t, enc.Encode(amf0.ECMAArray{
+			"width":        "640",
+			"duration":     float64(0),
+			"audiocodecid": float64(0),
+		}))
+
+		expected := &OnMetaData{
+			Duration:     float64Ptr(0),
+			AudioCodecID: float64Ptr(0),
+			Extra: map[string]interface{}{
+				"width": "640",
+			},
+		}
+
+		md, err := ReadOnMetaData(buf)
+		require.Nil(t, err)
+		require.Equal(t, expected, md)
+
+		require.Nil(t, WriteOnMetaData(buf, md))
+
+		md, err = ReadOnMetaData(buf)
+		require.Nil(t, err)
+		require.Equal(t, expected, md)
+		require.Equal(t, 0, buf.Len())
+	})
+
+	t.Run("different lengths of keyframes", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+
+		err := WriteOnMetaData(buf, &OnMetaData{
+			Keyframes: &Keyframes{
+				Times: []float64{0},
+			},
+		})
+		require.Error(t, err)
+		require.Equal(t, 0, buf.Len())
+	})
+}
@@ -0,0 +1,78 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package registry A registry of class names of Typed Objects. It is shared by the amf0 and the amf3 packages.
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var registry = struct {
+	sync.RWMutex
+	nameToType map[string]reflect.Type
+	typeToName map[reflect.Type]string
+}{
+	nameToType: make(map[string]reflect.Type),
+	typeToName: make(map[reflect.Type]string),
+}
+
+// Register Register a type of the value as a class. The type must be a struct or a pointer to a struct.
+// It returns an error if the class name or the type is already registered with the other one.
+func Register(className string, v interface{}) error {
+	if className == "" {
+		return fmt.Errorf("class name must not be empty")
+	}
+
+	ty := reflect.TypeOf(v)
+	if ty == nil {
+		return fmt.Errorf("cannot register nil")
+	}
+
+	structTy := ty
+	if structTy.Kind() == reflect.Ptr {
+		structTy = structTy.Elem()
+	}
+	if structTy.Kind() != reflect.Struct {
+		return fmt.Errorf("registered type must be a struct or a pointer to a struct: Type = %s", ty)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if registered, ok := registry.nameToType[className]; ok && registered != ty {
+		return fmt.Errorf("class name is already registered: ClassName = %s, Type = %s", className, registered)
+	}
+	if registered, ok := registry.typeToName[structTy]; ok && registered != className {
+		return fmt.Errorf("type is already registered: Type = %s, ClassName = %s", structTy, registered)
+	}
+
+	registry.nameToType[className] = ty
+	registry.typeToName[structTy] = className
+
+	return nil
+}
+
+// LookupType Returns the type which is registered with the class name
+func LookupType(className string) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	ty, ok := registry.nameToType[className]
+	return ty, ok
+}
+
+// LookupClassName Returns the class name which is registered with the struct type
+func LookupClassName(ty reflect.Type) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	name, ok := registry.typeToName[ty]
+	return name, ok
+}
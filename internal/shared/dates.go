@@ -0,0 +1,72 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package shared
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DateRounding A policy to encode sub-millisecond parts. It has the same values as amf0.DateRounding to be converted from it.
+type DateRounding int
+
+// Policies in the same order as amf0.DateRounding
+const (
+	DateRoundingReject DateRounding = iota
+	DateRoundingTruncate
+	DateRoundingRound
+	DateRoundingFraction
+)
+
+// TimeToMillis Converts time.Time into milliseconds since the Unix epoch. Sub-millisecond parts are encoded by the policy.
+func TimeToMillis(t time.Time, rounding DateRounding) (float64, error) {
+	ms := t.UnixMilli()
+	subMs := int64(t.Nanosecond()) % int64(time.Millisecond)
+
+	switch rounding {
+	case DateRoundingReject:
+		if subMs != 0 {
+			return 0, fmt.Errorf("date time of nano sec is not supported: Expected = 0, Actual = %d", subMs)
+		}
+		return float64(ms), nil
+
+	case DateRoundingTruncate:
+		return float64(ms), nil
+
+	case DateRoundingRound:
+		if subMs >= int64(time.Millisecond)/2 {
+			ms++
+		}
+		return float64(ms), nil
+
+	case DateRoundingFraction:
+		return float64(ms) + float64(subMs)/float64(time.Millisecond), nil
+
+	default:
+		return 0, fmt.Errorf("unknown date rounding: %d", rounding)
+	}
+}
+
+// MillisToTime Converts milliseconds since the Unix epoch into time.Time in UTC. Fractions are kept as nanoseconds.
+func MillisToTime(unixMs float64) (time.Time, error) {
+	// Accepts values which can be represented as int64 milliseconds
+	const minMillis = -float64(1 << 63)
+	const maxMillis = float64(1 << 63)
+
+	if math.IsNaN(unixMs) || unixMs < minMillis || unixMs >= maxMillis { // includes Infinity
+		return time.Time{}, &InvalidDateError{
+			Millis: unixMs,
+		}
+	}
+
+	ms := math.Floor(unixMs)
+	ns := math.Round((unixMs - ms) * float64(time.Millisecond))
+
+	return time.UnixMilli(int64(ms)).Add(time.Duration(ns)).In(time.UTC), nil
+}
@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package shared
+
+// DecoderOptions Limits of resources. It has the same fields as amf0.DecoderOptions to be converted from it.
+type DecoderOptions struct {
+	MaxDepth        int
+	MaxArrayLength  int
+	MaxStringLength int
+	MaxBytes        int64
+	MaxObjectKeys   int
+}
+
+// Limiter Counts resources which are consumed by a decoder and checks them against limits. Zero limits mean unlimited.
+type Limiter struct {
+	Options DecoderOptions
+
+	Nesting  int   // a nesting level of values which are being decoded
+	NumBytes int64 // bytes which are read since the decoder is created or reset
+}
+
+// LimitRead Truncates the buffer to bytes which can be read within MaxBytes. It fails if no bytes remain.
+// Bytes which are actually read must be added to NumBytes.
+func (l *Limiter) LimitRead(p []byte) ([]byte, error) {
+	if max := l.Options.MaxBytes; max > 0 {
+		remaining := max - l.NumBytes
+		if remaining <= 0 {
+			return nil, &LimitExceededError{
+				Limit:  "MaxBytes",
+				Max:    max,
+				Actual: l.NumBytes + int64(len(p)),
+			}
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	return p, nil
+}
+
+// Remaining Returns limits which remain for a value at the level. MaxDepth and MaxBytes are reduced by the level and bytes which are already read.
+func (l *Limiter) Remaining(level int) DecoderOptions {
+	o := l.Options
+	if o.MaxDepth > 0 {
+		o.MaxDepth -= level - 1
+	}
+	if o.MaxBytes > 0 {
+		o.MaxBytes -= l.NumBytes // Reads still fail even if no bytes remain and MaxBytes becomes 0
+	}
+
+	return o
+}
+
+// CheckDepth Checks the nesting level of the value
+func (l *Limiter) CheckDepth(level int) error {
+	if max := l.Options.MaxDepth; max > 0 && level > max {
+		return &LimitExceededError{
+			Limit:  "MaxDepth",
+			Max:    int64(max),
+			Actual: int64(level),
+		}
+	}
+
+	return nil
+}
+
+// CheckArrayLength Checks the number of elements of arrays
+func (l *Limiter) CheckArrayLength(length int64) error {
+	if max := l.Options.MaxArrayLength; max > 0 && length > int64(max) {
+		return &LimitExceededError{
+			Limit:  "MaxArrayLength",
+			Max:    int64(max),
+			Actual: length,
+		}
+	}
+
+	return nil
+}
+
+// CheckStringLength Checks the number of bytes of strings
+func (l *Limiter) CheckStringLength(length int64) error {
+	if max := l.Options.MaxStringLength; max > 0 && length > int64(max) {
+		return &LimitExceededError{
+			Limit:  "MaxStringLength",
+			Max:    int64(max),
+			Actual: length,
+		}
+	}
+
+	return nil
+}
+
+// CheckObjectKeys Checks the number of properties of the object including the property which is being decoded
+func (l *Limiter) CheckObjectKeys(numKeys int) error {
+	if max := l.Options.MaxObjectKeys; max > 0 && numKeys > max {
+		return &LimitExceededError{
+			Limit:  "MaxObjectKeys",
+			Max:    int64(max),
+			Actual: int64(numKeys),
+		}
+	}
+
+	return nil
+}
+
+// CheckRemainingBytes Checks that n bytes can be read within MaxBytes before buffers for them are allocated
+func (l *Limiter) CheckRemainingBytes(n int64) error {
+	if max := l.Options.MaxBytes; max > 0 && l.NumBytes+n > max {
+		return &LimitExceededError{
+			Limit:  "MaxBytes",
+			Max:    max,
+			Actual: l.NumBytes + n,
+		}
+	}
+
+	return nil
+}
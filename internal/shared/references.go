@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package shared
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ReferenceKey An identity of values which can be shared
+type ReferenceKey struct {
+	ptr uintptr
+	ty  reflect.Type
+	len int
+}
+
+// ReferenceKeyOf Returns the identity of the non-nil pointer, map or slice. Other values cannot be shared.
+func ReferenceKeyOf(rv reflect.Value) (ReferenceKey, bool) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if rv.IsNil() {
+			return ReferenceKey{}, false
+		}
+		return ReferenceKey{ptr: rv.Pointer(), ty: rv.Type()}, true
+
+	case reflect.Slice:
+		if rv.IsNil() {
+			return ReferenceKey{}, false
+		}
+		return ReferenceKey{ptr: rv.Pointer(), ty: rv.Type(), len: rv.Len()}, true
+
+	default:
+		return ReferenceKey{}, false
+	}
+}
+
+// References A table of complex values which are decoded. Values are referenced by indexes in the order they appear.
+type References []reflect.Value
+
+// Add Adds a complex value into the reference table. It must be called before decoding children.
+func (r *References) Add(rv reflect.Value) {
+	*r = append(*r, rv)
+}
+
+// Reserve Reserves an index of the reference table for a value which will be set after decoding children, or which is not decoded
+func (r *References) Reserve() int {
+	*r = append(*r, reflect.Value{})
+	return len(*r) - 1
+}
+
+// Assign Assigns the referenced value to the value which the pointer points to
+func (r References) Assign(index int, rv reflect.Value) error {
+	if index >= len(r) {
+		return &DecodeError{
+			Message: fmt.Sprintf("Reference index is out of range: Index = %d, Length = %d", index, len(r)),
+		}
+	}
+	ref := r[index]
+	if !ref.IsValid() {
+		return &DecodeError{
+			Message: fmt.Sprintf("Referenced value is being decoded or skipped: Index = %d", index),
+		}
+	}
+
+	if _, err := Indirect(rv); err != nil {
+		return err
+	}
+
+	// Share the referenced value if possible. Pointers are allocated until the value can be assigned.
+	rv = rv.Elem()
+	for {
+		if rv.Kind() == reflect.Ptr && ref.CanAddr() && ref.Addr().Type().AssignableTo(rv.Type()) {
+			rv.Set(ref.Addr())
+			return nil
+		}
+
+		if ref.Type().AssignableTo(rv.Type()) {
+			rv.Set(ref)
+			return nil
+		}
+
+		if rv.Kind() != reflect.Ptr {
+			break
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	return &NotAssignableError{
+		Message: fmt.Sprintf("Referenced value is not assignable: Type = %s", ref.Type()),
+		Kind:    rv.Kind(),
+		Type:    rv.Type(),
+	}
+}
@@ -0,0 +1,350 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package shared Helpers of encoders and decoders which do not depend on formats. It is shared by the amf0 and the amf3 packages.
+package shared
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Indirect Returns the value which the pointer points to. Nil pointers are allocated on the way.
+func Indirect(rv reflect.Value) (reflect.Value, error) {
+	if rv.Kind() != reflect.Ptr {
+		return reflect.Value{}, &NotAssignableError{
+			Message: "Not pointer",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+	if rv.IsNil() {
+		return reflect.Value{}, &NotAssignableError{
+			Message: "Nil",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	rv = rv.Elem()
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	return rv, nil
+}
+
+// SetNothing Sets nil to the reference type value. An interface will be set to undefined if it is not nil.
+func SetNothing(rv reflect.Value, undefined interface{}) error {
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		_, err := Indirect(rv) // returns a reason
+		return err
+	}
+	rv = rv.Elem() // Do not allocate pointers to set nil
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if undefined != nil && rv.NumMethod() == 0 {
+			rv.Set(reflect.ValueOf(undefined))
+			return nil
+		}
+		rv.Set(reflect.Zero(rv.Type()))
+
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		rv.Set(reflect.Zero(rv.Type()))
+
+	default:
+		if undefined != nil && rv.Type() == reflect.TypeOf(undefined) {
+			rv.Set(reflect.ValueOf(undefined))
+			return nil
+		}
+
+		return &NotAssignableError{
+			Message: "Not reference type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// SetBool Sets the boolean to the value which the pointer points to
+func SetBool(rv reflect.Value, b bool) error {
+	rv, err := Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool, reflect.Interface:
+		rv.Set(reflect.ValueOf(b).Convert(rv.Type()))
+
+	default:
+		return &NotAssignableError{
+			Message: "Not boolean type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// SetNumber Sets the number to the value which the pointer points to. The number is converted into the type of the value.
+func SetNumber(rv reflect.Value, num reflect.Value) error {
+	rv, err := Indirect(rv)
+	if err != nil {
+		return err
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fallthrough
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fallthrough
+	case reflect.Float32, reflect.Float64:
+		fallthrough
+	case reflect.Interface:
+		rv.Set(num.Convert(rv.Type()))
+
+	default:
+		return &NotAssignableError{
+			Message: "Not numeric type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// SetString Sets the string to the value. Values which implement encoding.TextUnmarshaler or encoding.BinaryUnmarshaler decode it by themselves.
+func SetString(rv reflect.Value, str string) error {
+	if rv.Kind() != reflect.Interface {
+		switch u := rv.Addr().Interface().(type) {
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText([]byte(str))
+		case encoding.BinaryUnmarshaler:
+			return u.UnmarshalBinary([]byte(str))
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(str)
+
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(str))
+
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			return &NotAssignableError{
+				Message: "Not byte slice type",
+				Kind:    rv.Kind(),
+				Type:    rv.Type(),
+			}
+		}
+		rv.SetBytes([]byte(str))
+
+	default:
+		return &NotAssignableError{
+			Message: "Not string type",
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// SetStringified Parses the string into the boolean or numeric value of fields tagged with the string option
+func SetStringified(rv reflect.Value, s string) error {
+	var err error
+	switch rv.Kind() {
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			rv.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, rv.Type().Bits()); err == nil {
+			rv.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, rv.Type().Bits()); err == nil {
+			rv.SetUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		var n float64
+		if n, err = strconv.ParseFloat(s, rv.Type().Bits()); err == nil {
+			rv.SetFloat(n)
+		}
+	default:
+		err = fmt.Errorf("not boolean or numeric type")
+	}
+	if err != nil {
+		return &NotAssignableError{
+			Message: fmt.Sprintf("Failed to parse a string: %+v", err),
+			Kind:    rv.Kind(),
+			Type:    rv.Type(),
+		}
+	}
+
+	return nil
+}
+
+// FormatStringified Formats the boolean or numeric value of fields tagged with the string option
+func FormatStringified(rv reflect.Value) (string, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits()), nil
+	default:
+		return "", &UnexpectedValueError{
+			Kind: rv.Kind(),
+		}
+	}
+}
+
+// InterfaceOf Returns the value as the interface if the value or the pointer to the value implements it
+func InterfaceOf(rv reflect.Value, ifaceTy reflect.Type) (interface{}, bool) {
+	if !rv.IsValid() || !rv.CanInterface() {
+		return nil, false
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		return nil, false // Interfaces will be checked after unwrapping
+
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil, false // Encoded as Null
+		}
+	}
+
+	if rv.Type().Implements(ifaceTy) {
+		return rv.Interface(), true
+	}
+
+	if rv.Kind() != reflect.Ptr && reflect.PtrTo(rv.Type()).Implements(ifaceTy) {
+		if rv.CanAddr() {
+			return rv.Addr().Interface(), true
+		}
+
+		// Copy the value to call methods which have pointer receivers
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return ptr.Interface(), true
+	}
+
+	return nil, false
+}
+
+// KeyName Returns a name of the map key. Keys must be strings or implement encoding.TextMarshaler.
+func KeyName(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+
+	if m, ok := InterfaceOf(key, textMarshalerType); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	}
+
+	return "", &UnexpectedKeyTypeError{
+		ActualKind: key.Kind(),
+		ExpectKind: reflect.String,
+	}
+}
+
+// IsStringKey Returns true if names of keys of the type are given by KeyName
+func IsStringKey(keyTy reflect.Type) bool {
+	return keyTy.Kind() == reflect.String || keyTy.Implements(textMarshalerType)
+}
+
+// MapEntry A pair of a name of the key and a value of maps
+type MapEntry struct {
+	Name  string
+	Value reflect.Value
+}
+
+// MapEntries Returns entries of the map which has string keys. They are sorted if less is not nil.
+func MapEntries(rv reflect.Value, less func(a, b string) bool) ([]MapEntry, error) {
+	if !rv.IsValid() || rv.IsNil() {
+		return nil, nil
+	}
+
+	entries := make([]MapEntry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		name, err := KeyName(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, MapEntry{Name: name, Value: iter.Value()})
+	}
+
+	if less != nil {
+		sort.Slice(entries, func(i, j int) bool {
+			return less(entries[i].Name, entries[j].Name)
+		})
+	}
+
+	return entries, nil
+}
+
+// CheckMapKeyType Key types of maps must be strings or implement encoding.TextUnmarshaler
+func CheckMapKeyType(keyTy reflect.Type) error {
+	if keyTy.Kind() == reflect.String || reflect.PtrTo(keyTy).Implements(textUnmarshalerType) {
+		return nil
+	}
+
+	return &NotAssignableError{
+		Message: "Key of map is not string type",
+		Kind:    keyTy.Kind(),
+		Type:    keyTy,
+	}
+}
+
+// MapKey Converts the key into a value of the key type
+func MapKey(keyTy reflect.Type, key string) (reflect.Value, error) {
+	if err := CheckMapKeyType(keyTy); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if keyTy.Kind() == reflect.String {
+		return reflect.ValueOf(key).Convert(keyTy), nil
+	}
+
+	k := reflect.New(keyTy)
+	if err := k.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+		return reflect.Value{}, err
+	}
+	return k.Elem(), nil
+}
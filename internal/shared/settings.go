@@ -0,0 +1,59 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package shared
+
+import (
+	"io"
+)
+
+// EncoderSettings Options of amf0.Encoder which also apply to AMF3 values
+type EncoderSettings struct {
+	KeyLess      func(a, b string) bool
+	DateRounding DateRounding
+}
+
+// DecoderSettings Options of amf0.Decoder which also apply to AMF3 values
+type DecoderSettings struct {
+	OrderedObjects bool
+	Limits         DecoderOptions // limits which remain for the next value
+}
+
+var (
+	// EncoderSettingsOf Returns settings of the writer if it is an amf0.Encoder. It is set by the amf0 package.
+	EncoderSettingsOf = func(w io.Writer) (EncoderSettings, bool) {
+		return EncoderSettings{}, false
+	}
+
+	// DecoderSettingsOf Returns settings of the reader if it is an amf0.Decoder. It is set by the amf0 package.
+	DecoderSettingsOf = func(r io.Reader) (DecoderSettings, bool) {
+		return DecoderSettings{}, false
+	}
+)
+
+// KeyPriority Returns a less function which orders the keys first in the order, and then the other keys in lexicographical order
+func KeyPriority(keys ...string) func(a, b string) bool {
+	priorities := make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, ok := priorities[key]; !ok {
+			priorities[key] = i
+		}
+	}
+
+	return func(a, b string) bool {
+		pa, okA := priorities[a]
+		pb, okB := priorities[b]
+		switch {
+		case okA && okB:
+			return pa < pb
+		case okA || okB:
+			return okA
+		default:
+			return a < b
+		}
+	}
+}
@@ -0,0 +1,317 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package structfields Resolves fields of structs by struct tags. It is shared by the amf0 and the amf3 packages.
+package structfields
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field A struct field which is encoded/decoded as a property of objects
+type Field struct {
+	Name      string
+	Index     []int
+	Tagged    bool
+	OmitEmpty bool
+	AsString  bool
+	Rest      bool
+}
+
+// Fields Fields of a struct type which are shared by encoders and decoders
+type Fields struct {
+	List   []Field
+	ByName map[string]*Field
+}
+
+type cacheKey struct {
+	ty   reflect.Type
+	tags string
+}
+
+var fieldCache sync.Map // map[cacheKey]*Fields
+
+// Cached Returns fields of the struct type resolved by the tags. Results are cached per type and tags.
+func Cached(ty reflect.Type, tags ...string) *Fields {
+	key := cacheKey{ty: ty, tags: strings.Join(tags, ",")}
+	if fs, ok := fieldCache.Load(key); ok {
+		return fs.(*Fields)
+	}
+
+	fs, _ := fieldCache.LoadOrStore(key, typeFields(ty, tags))
+	return fs.(*Fields)
+}
+
+// lookupTag Returns the value of the first tag which exists in the field
+func lookupTag(sf reflect.StructField, tags []string) string {
+	for _, tag := range tags {
+		if v, ok := sf.Tag.Lookup(tag); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// typeFields Resolves fields of the struct type by the tags such as `amf0`. If a field has multiple tags, the first one in tags is used.
+//
+// The tag is formatted as `amf0:"name,opt1,opt2"`. Name is used as a key of the property instead of the field name if it is specified.
+// Fields which have the tag `amf0:"-"` and unexported fields are ignored.
+// Options are:
+//   - omitempty: The field is omitted when encoding if it has an empty value
+//   - string: The field of a boolean or numeric type is encoded as a String, and decoded from a String
+//   - rest: The field of a slice type gathers trailing values of sequences. See amf0.Decoder.DecodeSequence
+//
+// Fields of anonymous struct fields (or pointers to structs) which are not tagged with names are flattened into the parent
+// as well as encoding/json. If multiple fields have the same name, the shallowest one is used, and then the tagged one.
+// If there are still multiple candidates, all of them are ignored.
+func typeFields(ty reflect.Type, tags []string) *Fields {
+	type entry struct {
+		ty    reflect.Type
+		index []int
+	}
+
+	var current []entry
+	next := []entry{{ty: ty}}
+
+	// Count of queued types for the current and next level
+	var count map[reflect.Type]int
+	nextCount := map[reflect.Type]int{}
+
+	visited := map[reflect.Type]bool{}
+
+	var list []Field
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.ty] {
+				continue
+			}
+			visited[e.ty] = true
+
+			for i := 0; i < e.ty.NumField(); i++ {
+				sf := e.ty.Field(i)
+				if sf.Anonymous {
+					t := sf.Type
+					if t.Kind() == reflect.Ptr {
+						t = t.Elem()
+					}
+					if !sf.IsExported() && t.Kind() != reflect.Struct {
+						continue
+					}
+					// Unexported struct types may have exported fields
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				tag := lookupTag(sf, tags)
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				// Flatten anonymous structs which are not named by tags
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, entry{ty: ft, index: index})
+					}
+					continue
+				}
+
+				if !sf.IsExported() {
+					continue
+				}
+
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+
+				asString := false
+				if opts.Contains("string") {
+					switch sf.Type.Kind() {
+					case reflect.Bool,
+						reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+						reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+						reflect.Float32, reflect.Float64:
+						asString = true
+					}
+				}
+
+				f := Field{
+					Name:      name,
+					Index:     index,
+					Tagged:    tagged,
+					OmitEmpty: opts.Contains("omitempty"),
+					AsString:  asString,
+					Rest:      opts.Contains("rest") && sf.Type.Kind() == reflect.Slice,
+				}
+				list = append(list, f)
+				if count[e.ty] > 1 {
+					// The struct is embedded multiple times at the same level, thus the field conflicts with itself
+					list = append(list, f)
+				}
+			}
+		}
+	}
+
+	return newFields(dominantFields(list))
+}
+
+// dominantFields Removes fields which are hidden or have conflicted names. Fields are sorted by the index sequence.
+func dominantFields(list []Field) []Field {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		if len(list[i].Index) != len(list[j].Index) {
+			return len(list[i].Index) < len(list[j].Index)
+		}
+		if list[i].Tagged != list[j].Tagged {
+			return list[i].Tagged
+		}
+		return lessIndex(list[i].Index, list[j].Index)
+	})
+
+	out := list[:0]
+	for i := 0; i < len(list); {
+		// A group of fields which have the same name
+		j := i + 1
+		for j < len(list) && list[j].Name == list[i].Name {
+			j++
+		}
+
+		group := list[i:j]
+		if len(group) == 1 ||
+			len(group[0].Index) != len(group[1].Index) ||
+			group[0].Tagged != group[1].Tagged {
+			out = append(out, group[0])
+		}
+		i = j
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return lessIndex(out[i].Index, out[j].Index)
+	})
+
+	return out
+}
+
+func lessIndex(a, b []int) bool {
+	for k, x := range a {
+		if k >= len(b) {
+			return false
+		}
+		if x != b[k] {
+			return x < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// ByIndex Returns the field of the struct. It returns false if the field is in a nil embedded pointer.
+func ByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+
+	return rv, true
+}
+
+// ByIndexAlloc Returns the field of the struct. Nil embedded pointers are allocated.
+// If the nil pointer cannot be allocated because it is a pointer to an unexported struct, it returns the pointer and false.
+func ByIndexAlloc(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return rv, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+
+	return rv, true
+}
+
+func newFields(list []Field) *Fields {
+	fs := &Fields{
+		List:   list,
+		ByName: make(map[string]*Field, len(list)),
+	}
+	for i := range fs.List {
+		fs.ByName[fs.List[i].Name] = &fs.List[i]
+	}
+
+	return fs
+}
+
+type tagOptions string
+
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, tagOptions("")
+}
+
+func (o tagOptions) Contains(name string) bool {
+	s := string(o)
+	for s != "" {
+		var next string
+		if i := strings.Index(s, ","); i != -1 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == name {
+			return true
+		}
+		s = next
+	}
+	return false
+}
+
+// IsEmptyValue Reports whether the value is empty for the omitempty option
+func IsEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
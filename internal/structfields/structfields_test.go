@@ -0,0 +1,51 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package structfields
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTag(t *testing.T) {
+	name, opts := parseTag("a,omitempty,string")
+	require.Equal(t, "a", name)
+	require.True(t, opts.Contains("omitempty"))
+	require.True(t, opts.Contains("string"))
+	require.False(t, opts.Contains("omit"))
+
+	name, opts = parseTag(",string")
+	require.Equal(t, "", name)
+	require.True(t, opts.Contains("string"))
+
+	name, opts = parseTag("a")
+	require.Equal(t, "a", name)
+	require.False(t, opts.Contains("a"))
+}
+
+func TestCachedTags(t *testing.T) {
+	type s struct {
+		A string `amf0:"a"`
+		B string `amf0:"b" amf3:"x"`
+		C string `amf0:"-" amf3:"c"`
+		D string `amf3:"-"`
+	}
+
+	namesOf := func(tags ...string) []string {
+		var names []string
+		for _, f := range Cached(reflect.TypeOf(s{}), tags...).List {
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"a", "b", "D"}, namesOf("amf0"))
+	require.Equal(t, []string{"a", "x", "c"}, namesOf("amf3", "amf0"))
+}
@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// RawMessage A raw encoded AMF0 value. It can be used to delay decoding or to pass values through as is.
+// References in raw values are kept as is, thus they are valid only in the original message.
+type RawMessage []byte
+
+// MarshalAMF0 Writes the raw value as is. nil is written as Null.
+func (m RawMessage) MarshalAMF0(enc *Encoder) error {
+	if m == nil {
+		return enc.encodeNull()
+	}
+
+	// Raw values must be parsed to count complex values when references are used
+	if enc.validateRawMessage || enc.useReferences {
+		numObjects, err := countRawObjects(m)
+		if err != nil {
+			return err
+		}
+		enc.numObjects += numObjects
+	}
+
+	_, err := enc.Write(m)
+	return err
+}
+
+// UnmarshalAMF0 Captures bytes of the next value
+func (m *RawMessage) UnmarshalAMF0(dec *Decoder) error {
+	if m == nil {
+		return fmt.Errorf("RawMessage: UnmarshalAMF0 on nil pointer")
+	}
+
+	data, err := dec.readRaw()
+	if err != nil {
+		return err
+	}
+	*m = append((*m)[0:0], data...)
+
+	return nil
+}
+
+// readRaw Reads bytes of the next value at the current nesting level. Complex values in it are reserved in the reference table.
+func (dec *Decoder) readRaw() ([]byte, error) {
+	return dec.capturing(dec.skipValue)
+}
+
+// capturing Returns bytes which are read by the function. Captures can be nested.
+func (dec *Decoder) capturing(read func() error) ([]byte, error) {
+	prev := dec.capture
+	buf := &bytes.Buffer{}
+
+	dec.capture = buf
+	err := read()
+	dec.capture = prev
+
+	if prev != nil {
+		prev.Write(buf.Bytes())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// countRawObjects Checks that the data is a single complete value and returns the number of complex values in it
+func countRawObjects(data []byte) (int, error) {
+	r := bytes.NewReader(data)
+	dec := NewDecoder(r)
+
+	if err := dec.skip(); err != nil {
+		return 0, &DecodeError{
+			Message: fmt.Sprintf("Invalid RawMessage: %+v", err),
+			Dump:    hex.Dump(data),
+		}
+	}
+	if r.Len() != 0 {
+		return 0, &DecodeError{
+			Message: fmt.Sprintf("Invalid RawMessage: %d bytes remain", r.Len()),
+			Dump:    hex.Dump(data),
+		}
+	}
+
+	return len(dec.refs), nil
+}
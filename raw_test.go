@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRawMessage(t *testing.T) {
+	allTestCases := append(append([]testCase{}, testCases...), objectTest, referenceTest, typedObjectTest, orderedECMAArrayTest, avmPlusObjectTest)
+
+	for _, tc := range allTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			r := bytes.NewReader(append(append([]byte{}, tc.Binary...), 0x05)) // Null follows
+			dec := NewDecoder(r)
+
+			var m RawMessage
+			err := dec.Decode(&m)
+			require.Nil(t, err)
+			require.Equal(t, RawMessage(tc.Binary), m)
+
+			var v interface{}
+			err = dec.Decode(&v)
+			require.Nil(t, err)
+			require.Nil(t, v)
+		})
+	}
+
+	t.Run("command", func(t *testing.T) {
+		bin := []byte{
+			0x02, 0x00, 0x07, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, // String(connect)
+			0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+		}
+		bin = append(bin, orderedObjectTest.Binary...)
+		bin = append(bin, 0x05) // Null
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var name string
+		err := dec.Decode(&name)
+		require.Nil(t, err)
+
+		var transactionID int
+		err = dec.Decode(&transactionID)
+		require.Nil(t, err)
+
+		var args []RawMessage
+		for r.Len() > 0 {
+			var m RawMessage
+			err := dec.Decode(&m)
+			require.Nil(t, err)
+			args = append(args, m)
+		}
+		require.Equal(t, []RawMessage{orderedObjectTest.Binary, {0x05}}, args)
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		type message struct {
+			A RawMessage  `amf0:"a"`
+			B *RawMessage `amf0:"b"`
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+		err := NewEncoder(buf).Encode(OrderedObject{
+			{Key: "a", Value: Undefined},
+			{Key: "b", Value: map[string]interface{}{"c": "d"}},
+		})
+		require.Nil(t, err)
+
+		var m message
+		err = NewDecoder(buf).Decode(&m)
+		require.Nil(t, err)
+		require.Equal(t, RawMessage{0x06}, m.A)
+		require.NotNil(t, m.B)
+
+		var v map[string]interface{}
+		err = NewDecoder(bytes.NewReader(*m.B)).Decode(&v)
+		require.Nil(t, err)
+		require.Equal(t, map[string]interface{}{"c": "d"}, v)
+	})
+
+	t.Run("unexpected EOF", func(t *testing.T) {
+		r := bytes.NewReader(objectTest.Binary[:len(objectTest.Binary)-1])
+		dec := NewDecoder(r)
+
+		var m RawMessage
+		err := dec.Decode(&m)
+		require.Error(t, err)
+	})
+}
+
+func TestEncodeRawMessage(t *testing.T) {
+	t.Run("verbatim", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.Encode([]interface{}{"a", RawMessage(objectTest.Binary)})
+		require.Nil(t, err)
+
+		expected := []byte{
+			0x0a,                   // Strict Array Marker
+			0x00, 0x00, 0x00, 0x02, // Array length (2: u32) BigEndian
+			0x02, 0x00, 0x01, 0x61, // String(a)
+		}
+		expected = append(expected, objectTest.Binary...)
+		require.Equal(t, expected, buf.Bytes())
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		var m RawMessage
+		err := enc.Encode(m)
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x05}, buf.Bytes())
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		cases := []RawMessage{
+			{},
+			objectTest.Binary[:len(objectTest.Binary)-1],
+			append(append(RawMessage{}, objectTest.Binary...), 0x05),
+		}
+
+		for _, m := range cases {
+			enc := NewEncoder(bytes.NewBuffer([]byte{}))
+			err := enc.Encode(m)
+			require.Nil(t, err) // Not validated by default
+
+			enc = NewEncoder(bytes.NewBuffer([]byte{}), WithValidateRawMessage())
+			err = enc.Encode(m)
+			require.IsType(t, &DecodeError{}, err)
+		}
+	})
+
+	t.Run("reference", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf, WithReferences())
+
+		o := &sampleObject{A: "s", B: 42}
+		err := enc.Encode([]interface{}{RawMessage(objectTest.Binary), o, o})
+		require.Nil(t, err)
+
+		// 0: the array, 1: the raw object, 2: o
+		require.Equal(t, []byte{0x07, 0x00, 0x02}, buf.Bytes()[buf.Len()-3:])
+	})
+}
@@ -0,0 +1,33 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"reflect"
+
+	"github.com/yutopp/go-amf0/internal/registry"
+)
+
+// RegisterType Register a type of the value as a class of Typed Objects
+// Values of the type are encoded as Typed Objects which have the class name, and Typed Objects which have the class name are
+// decoded into values of the type when the receiver is interface{}. The type must be a struct or a pointer to a struct.
+// Registered classes are shared with the amf3 package.
+// It panics if the class name or the type is already registered with the other one.
+func RegisterType(className string, v interface{}) {
+	if err := registry.Register(className, v); err != nil {
+		panic("amf0: " + err.Error())
+	}
+}
+
+func lookupRegisteredType(className string) (reflect.Type, bool) {
+	return registry.LookupType(className)
+}
+
+func lookupRegisteredClassName(ty reflect.Type) (string, bool) {
+	return registry.LookupClassName(ty)
+}
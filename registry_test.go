@@ -0,0 +1,46 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterType(t *testing.T) {
+	t.Run("registering the same pair again is allowed", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			RegisterType("com.example.User", sampleUser{})
+		})
+	})
+
+	t.Run("class name is already registered", func(t *testing.T) {
+		require.Panics(t, func() {
+			RegisterType("com.example.User", sampleObject{})
+		})
+	})
+
+	t.Run("type is already registered", func(t *testing.T) {
+		require.Panics(t, func() {
+			RegisterType("com.example.Other", sampleUser{})
+		})
+	})
+
+	t.Run("not struct", func(t *testing.T) {
+		require.Panics(t, func() {
+			RegisterType("com.example.Int", 1)
+		})
+	})
+
+	t.Run("empty class name", func(t *testing.T) {
+		require.Panics(t, func() {
+			RegisterType("", sampleUser{})
+		})
+	})
+}
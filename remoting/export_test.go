@@ -0,0 +1,43 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package remoting
+
+var packet = &Packet{
+	Version: 0,
+	Headers: []Header{
+		{
+			Name:  "Credentials",
+			Value: map[string]interface{}{"userid": "alice"},
+		},
+	},
+	Messages: []Message{
+		{
+			TargetURI:   "Board.add",
+			ResponseURI: "/1",
+			Value:       []interface{}{float64(1), float64(2)},
+		},
+	},
+}
+
+var packetBinary = []byte{
+	0x00, 0x00, // Version(0)
+	0x00, 0x01, // Header Count(1)
+	0x00, 0x0b, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, //   Name(Credentials)
+	0x00, 0x00, 0x00, 0x00, 0x14, //   Must Understand(false), Length(20)
+	0x03,                                           //   Object Marker
+	0x00, 0x06, 0x75, 0x73, 0x65, 0x72, 0x69, 0x64, //     Key(userid)
+	0x02, 0x00, 0x05, 0x61, 0x6c, 0x69, 0x63, 0x65, //     String(alice)
+	0x00, 0x00, 0x09, //     End
+	0x00, 0x01, // Message Count(1)
+	0x00, 0x09, 0x42, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x61, 0x64, 0x64, //   Target URI(Board.add)
+	0x00, 0x02, 0x2f, 0x31, //   Response URI(/1)
+	0x00, 0x00, 0x00, 0x17, //   Length(23)
+	0x0a, 0x00, 0x00, 0x00, 0x02, //   Strict Array Marker, Length(2)
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //     Number(1)
+	0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //     Number(2)
+}
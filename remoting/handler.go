@@ -0,0 +1,213 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package remoting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/yutopp/go-amf0"
+)
+
+// ContentType A content type of AMF packets
+const ContentType = "application/x-amf"
+
+const (
+	// SuffixOnResult A suffix of target URIs of successful responses
+	SuffixOnResult = "/onResult"
+	// SuffixOnStatus A suffix of target URIs of failure responses
+	SuffixOnStatus = "/onStatus"
+)
+
+// HandlerFunc A function which handles a message. Args are elements of the message value if it is an array,
+// otherwise the value itself. Returned errors are sent to clients as Faults.
+type HandlerFunc func(r *http.Request, args []interface{}) (interface{}, error)
+
+// Fault An error which is sent to clients by onStatus responses
+type Fault struct {
+	Level       string      `amf0:"level"`
+	Code        string      `amf0:"code"`
+	Description string      `amf0:"description"`
+	Details     interface{} `amf0:"details,omitempty"`
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("Fault: Code = %s, Description = %s", f.Code, f.Description)
+}
+
+const (
+	// DefaultMaxBodyBytes The maximum size of request bodies which is used if WithMaxBodyBytes is not specified
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultMaxDepth The maximum nesting level of values which is used if WithDecoderOptions is not specified
+	DefaultMaxDepth = 64
+)
+
+// ServeMux An http.Handler which routes messages of AMF packets to functions by their target URIs
+type ServeMux struct {
+	m        sync.RWMutex
+	handlers map[string]HandlerFunc
+
+	maxBodyBytes int64
+	limits       amf0.DecoderOptions
+}
+
+// ServeMuxOption An option for ServeMux
+type ServeMuxOption func(*ServeMux)
+
+// WithMaxBodyBytes Limit the size of request bodies. Requests which exceed it or MaxBytes of DecoderOptions are responded by 413 Request Entity Too Large.
+// Zero means unlimited.
+func WithMaxBodyBytes(n int64) ServeMuxOption {
+	return func(mux *ServeMux) {
+		mux.maxBodyBytes = n
+	}
+}
+
+// WithDecoderOptions Limit resources which are consumed to decode each value of requests.
+// MaxBytes is bounded by the maximum size of request bodies.
+func WithDecoderOptions(o amf0.DecoderOptions) ServeMuxOption {
+	return func(mux *ServeMux) {
+		mux.limits = o
+	}
+}
+
+// NewServeMux Create a new instance of ServeMux
+// Request bodies are untrusted, thus DefaultMaxBodyBytes and DefaultMaxDepth are applied unless options are specified.
+func NewServeMux(opts ...ServeMuxOption) *ServeMux {
+	mux := &ServeMux{
+		handlers:     make(map[string]HandlerFunc),
+		maxBodyBytes: DefaultMaxBodyBytes,
+		limits: amf0.DecoderOptions{
+			MaxDepth: DefaultMaxDepth,
+		},
+	}
+	for _, opt := range opts {
+		opt(mux)
+	}
+
+	return mux
+}
+
+// HandleFunc Register the function for the target URI such as "Service.method"
+func (mux *ServeMux) HandleFunc(targetURI string, f HandlerFunc) {
+	mux.m.Lock()
+	defer mux.m.Unlock()
+
+	mux.handlers[targetURI] = f
+}
+
+func (mux *ServeMux) handler(targetURI string) (HandlerFunc, bool) {
+	mux.m.RLock()
+	defer mux.m.RUnlock()
+
+	f, ok := mux.handlers[targetURI]
+	return f, ok
+}
+
+// ServeHTTP Implements http.Handler. Each message of the request is responded by a message which target URI is
+// the response URI followed by SuffixOnResult or SuffixOnStatus.
+func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body io.Reader = r.Body
+	var lr *io.LimitedReader
+	limits := mux.limits
+	if max := mux.maxBodyBytes; max > 0 {
+		// One more byte is read to detect bodies which exceed the limit
+		lr = &io.LimitedReader{R: r.Body, N: max + 1}
+		body = lr
+		if limits.MaxBytes == 0 || limits.MaxBytes > max {
+			limits.MaxBytes = max // Values are not allocated beyond the body
+		}
+	}
+
+	req, err := ReadPacket(body, amf0.WithDecoderOptions(limits))
+	if lr != nil && lr.N == 0 {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), statusOf(err))
+		return
+	}
+
+	res := &Packet{
+		Version: req.Version,
+	}
+	for _, m := range req.Messages {
+		res.Messages = append(res.Messages, mux.serveMessage(r, &m))
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := WritePacket(buf, res); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// statusOf Returns a status code for errors of reading requests
+func statusOf(err error) int {
+	var limitErr *amf0.LimitExceededError
+	if errors.As(err, &limitErr) && limitErr.Limit == "MaxBytes" {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+func (mux *ServeMux) serveMessage(r *http.Request, m *Message) Message {
+	res := Message{
+		TargetURI:   m.ResponseURI + SuffixOnResult,
+		ResponseURI: "null",
+	}
+
+	f, ok := mux.handler(m.TargetURI)
+	if !ok {
+		res.TargetURI = m.ResponseURI + SuffixOnStatus
+		res.Value = &Fault{
+			Level:       "error",
+			Code:        "Server.ResourceNotFound",
+			Description: fmt.Sprintf("Service not found: TargetURI = %s", m.TargetURI),
+		}
+		return res
+	}
+
+	args, ok := m.Value.([]interface{})
+	if !ok {
+		args = []interface{}{m.Value}
+	}
+
+	value, err := f(r, args)
+	if err != nil {
+		fault, ok := err.(*Fault)
+		if !ok {
+			fault = &Fault{
+				Level:       "error",
+				Code:        "Server.Processing",
+				Description: err.Error(),
+			}
+		}
+
+		res.TargetURI = m.ResponseURI + SuffixOnStatus
+		res.Value = fault
+		return res
+	}
+
+	res.Value = value
+	return res
+}
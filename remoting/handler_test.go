@@ -0,0 +1,212 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package remoting
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func newTestServer() *httptest.Server {
+	mux := NewServeMux()
+	mux.HandleFunc("Board.add", func(r *http.Request, args []interface{}) (interface{}, error) {
+		sum := float64(0)
+		for _, arg := range args {
+			n, ok := arg.(float64)
+			if !ok {
+				return nil, &Fault{Level: "error", Code: "Board.InvalidArgument", Description: "not a number"}
+			}
+			sum += n
+		}
+		return sum, nil
+	})
+	mux.HandleFunc("Board.clear", func(r *http.Request, args []interface{}) (interface{}, error) {
+		return nil, errors.New("locked")
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func post(t *testing.T, url string, req *Packet) *Packet {
+	buf := bytes.NewBuffer([]byte{})
+	require.Nil(t, WritePacket(buf, req))
+
+	resp, err := http.Post(url, ContentType, buf)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+
+	res, err := ReadPacket(resp.Body)
+	require.Nil(t, err)
+
+	return res
+}
+
+func TestServeMux(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	res := post(t, ts.URL, &Packet{
+		Version: 3,
+		Messages: []Message{
+			{TargetURI: "Board.add", ResponseURI: "/1", Value: []interface{}{1, 2}},
+			{TargetURI: "Board.add", ResponseURI: "/2", Value: "x"},
+			{TargetURI: "Board.clear", ResponseURI: "/3", Value: []interface{}{}},
+			{TargetURI: "Board.unknown", ResponseURI: "/4", Value: []interface{}{}},
+		},
+	})
+
+	require.Equal(t, &Packet{
+		Version: 3,
+		Messages: []Message{
+			{
+				TargetURI:   "/1/onResult",
+				ResponseURI: "null",
+				Value:       float64(3),
+			},
+			{
+				TargetURI:   "/2/onStatus",
+				ResponseURI: "null",
+				Value: map[string]interface{}{
+					"level":       "error",
+					"code":        "Board.InvalidArgument",
+					"description": "not a number",
+				},
+			},
+			{
+				TargetURI:   "/3/onStatus",
+				ResponseURI: "null",
+				Value: map[string]interface{}{
+					"level":       "error",
+					"code":        "Server.Processing",
+					"description": "locked",
+				},
+			},
+			{
+				TargetURI:   "/4/onStatus",
+				ResponseURI: "null",
+				Value: map[string]interface{}{
+					"level":       "error",
+					"code":        "Server.ResourceNotFound",
+					"description": "Service not found: TargetURI = Board.unknown",
+				},
+			},
+		},
+	}, res)
+}
+
+func TestServeMuxErrors(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	t.Run("method", func(t *testing.T) {
+		resp, err := http.Get(ts.URL)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("broken packet", func(t *testing.T) {
+		resp, err := http.Post(ts.URL, ContentType, bytes.NewReader([]byte{0x00}))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("huge array", func(t *testing.T) {
+		bin := []byte{
+			0x00, 0x00, // Version(0)
+			0x00, 0x00, // Header count(0)
+			0x00, 0x01, // Message count(1)
+			0x00, 0x09, 0x42, 0x6f, 0x61, 0x72, 0x64, 0x2e, 0x61, 0x64, 0x64, // Target URI(Board.add)
+			0x00, 0x02, 0x2f, 0x31, // Response URI(/1)
+			0xff, 0xff, 0xff, 0xff, // Length(unknown)
+			0x0a, 0x7f, 0xff, 0xff, 0xff, // StrictArray(length 2147483647) without elements
+		}
+		resp, err := http.Post(ts.URL, ContentType, bytes.NewReader(bin))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode) // Elements cannot be in the body
+	})
+}
+
+func TestServeMuxLimits(t *testing.T) {
+	req := &Packet{
+		Messages: []Message{
+			{TargetURI: "Board.add", ResponseURI: "/1", Value: []interface{}{float64(1), float64(2)}},
+		},
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	require.Nil(t, WritePacket(buf, req))
+	bin := buf.Bytes()
+
+	for _, tc := range []struct {
+		name   string
+		opts   []ServeMuxOption
+		status int
+	}{
+		{
+			name:   "default",
+			status: http.StatusOK,
+		},
+		{
+			name:   "MaxBodyBytes",
+			opts:   []ServeMuxOption{WithMaxBodyBytes(16)},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "MaxBodyBytes across values",
+			opts:   []ServeMuxOption{WithMaxBodyBytes(int64(len(bin) - 1))},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "MaxBodyBytes just fits",
+			opts:   []ServeMuxOption{WithMaxBodyBytes(int64(len(bin)))},
+			status: http.StatusOK,
+		},
+		{
+			name:   "MaxDepth",
+			opts:   []ServeMuxOption{WithDecoderOptions(amf0.DecoderOptions{MaxDepth: 1})},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "MaxArrayLength",
+			opts:   []ServeMuxOption{WithDecoderOptions(amf0.DecoderOptions{MaxArrayLength: 1})},
+			status: http.StatusBadRequest,
+		},
+	} {
+		tc := tc // capture
+
+		t.Run(tc.name, func(t *testing.T) {
+			mux := NewServeMux(tc.opts...)
+			mux.HandleFunc("Board.add", func(r *http.Request, args []interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			resp, err := http.Post(ts.URL, ContentType, bytes.NewReader(bin))
+			require.Nil(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
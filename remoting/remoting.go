@@ -0,0 +1,245 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package remoting Readers and writers of AMF packets which are used by Flash Remoting over HTTP
+package remoting
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/yutopp/go-amf0"
+)
+
+// unknownLength A length of values which is used when senders do not know it
+const unknownLength = math.MaxUint32
+
+// Packet An AMF packet
+type Packet struct {
+	Version  uint16 // 0 for AMF0 clients, 3 for AMF3 clients
+	Headers  []Header
+	Messages []Message
+}
+
+// Header A header of AMF packets
+type Header struct {
+	Name           string
+	MustUnderstand bool
+	Value          interface{}
+}
+
+// Message A message of AMF packets. Values of requests are usually Strict Arrays of arguments.
+type Message struct {
+	TargetURI   string
+	ResponseURI string
+	Value       interface{}
+}
+
+// ReadPacket Read an AMF packet.
+// Lengths of values are ignored because they may be unknown, and values are decoded from the reader directly.
+// References are resolved within each value. Options are applied to the decoder of values, such as amf0.WithDecoderOptions to limit resources.
+func ReadPacket(r io.Reader, opts ...amf0.DecoderOption) (*Packet, error) {
+	dec := amf0.NewDecoder(r, opts...)
+
+	var version uint16
+	if err := binary.Read(dec, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+
+	p := &Packet{
+		Version: version,
+	}
+
+	var headerCount uint16
+	if err := binary.Read(dec, binary.BigEndian, &headerCount); err != nil {
+		return nil, wrapEOF(err)
+	}
+	for i := 0; i < int(headerCount); i++ {
+		name, err := readUTF8(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		var fields struct {
+			MustUnderstand uint8
+			Length         uint32
+		}
+		if err := binary.Read(dec, binary.BigEndian, &fields); err != nil {
+			return nil, wrapEOF(err)
+		}
+
+		value, err := readValue(dec, r)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Headers = append(p.Headers, Header{
+			Name:           name,
+			MustUnderstand: fields.MustUnderstand != 0,
+			Value:          value,
+		})
+	}
+
+	var messageCount uint16
+	if err := binary.Read(dec, binary.BigEndian, &messageCount); err != nil {
+		return nil, wrapEOF(err)
+	}
+	for i := 0; i < int(messageCount); i++ {
+		targetURI, err := readUTF8(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		responseURI, err := readUTF8(dec)
+		if err != nil {
+			return nil, err
+		}
+
+		var length uint32
+		if err := binary.Read(dec, binary.BigEndian, &length); err != nil {
+			return nil, wrapEOF(err)
+		}
+
+		value, err := readValue(dec, r)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Messages = append(p.Messages, Message{
+			TargetURI:   targetURI,
+			ResponseURI: responseURI,
+			Value:       value,
+		})
+	}
+
+	return p, nil
+}
+
+func readValue(dec *amf0.Decoder, r io.Reader) (interface{}, error) {
+	dec.Reset(r) // References are not shared between values
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, wrapEOF(err)
+	}
+
+	return value, nil
+}
+
+// WritePacket Write the AMF packet. Lengths of values are always written.
+func WritePacket(w io.Writer, p *Packet) error {
+	if len(p.Headers) > math.MaxUint16 {
+		return fmt.Errorf("too many headers: Expected <= %d, Actual = %d", math.MaxUint16, len(p.Headers))
+	}
+	if len(p.Messages) > math.MaxUint16 {
+		return fmt.Errorf("too many messages: Expected <= %d, Actual = %d", math.MaxUint16, len(p.Messages))
+	}
+
+	if err := binary.Write(w, binary.BigEndian, p.Version); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint16(len(p.Headers))); err != nil {
+		return err
+	}
+	for _, h := range p.Headers {
+		if err := writeUTF8(w, h.Name); err != nil {
+			return err
+		}
+
+		var mustUnderstand uint8
+		if h.MustUnderstand {
+			mustUnderstand = 1
+		}
+		if err := binary.Write(w, binary.BigEndian, mustUnderstand); err != nil {
+			return err
+		}
+
+		if err := writeValue(w, h.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint16(len(p.Messages))); err != nil {
+		return err
+	}
+	for _, m := range p.Messages {
+		if err := writeUTF8(w, m.TargetURI); err != nil {
+			return err
+		}
+
+		if err := writeUTF8(w, m.ResponseURI); err != nil {
+			return err
+		}
+
+		if err := writeValue(w, m.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeValue Writes the value prefixed by its length
+func writeValue(w io.Writer, value interface{}) error {
+	buf := bytes.NewBuffer([]byte{})
+	enc := amf0.NewEncoder(buf)
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+
+	if uint64(buf.Len()) >= unknownLength {
+		return fmt.Errorf("too large value: Expected < %d, Actual = %d", uint32(unknownLength), buf.Len())
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint32(buf.Len())); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readUTF8 Reads a string which is prefixed by the length as u16
+func readUTF8(r io.Reader) (string, error) {
+	var l uint16
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return "", wrapEOF(err)
+	}
+
+	str := make([]byte, l)
+	if _, err := io.ReadFull(r, str); err != nil {
+		return "", wrapEOF(err)
+	}
+
+	return string(str), nil
+}
+
+// writeUTF8 Writes a string which is prefixed by the length as u16
+func writeUTF8(w io.Writer, str string) error {
+	if len(str) > math.MaxUint16 {
+		return fmt.Errorf("too long string: Expected <= %d, Actual = %d", math.MaxUint16, len(str))
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint16(len(str))); err != nil {
+		return err
+	}
+
+	_, err := w.Write([]byte(str))
+	return err
+}
+
+func wrapEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
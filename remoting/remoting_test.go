@@ -0,0 +1,63 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package remoting
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPacket(t *testing.T) {
+	t.Run("packet", func(t *testing.T) {
+		r := bytes.NewReader(packetBinary)
+
+		p, err := ReadPacket(r)
+		require.Nil(t, err)
+		require.Equal(t, packet, p)
+		require.Equal(t, 0, r.Len())
+	})
+
+	t.Run("unknown length", func(t *testing.T) {
+		bin := []byte{
+			0x00, 0x03, // Version(3)
+			0x00, 0x00, // Header Count(0)
+			0x00, 0x01, // Message Count(1)
+			0x00, 0x01, 0x61, //   Target URI(a)
+			0x00, 0x02, 0x2f, 0x32, //   Response URI(/2)
+			0xff, 0xff, 0xff, 0xff, //   Length(unknown)
+			0x05, //   Null
+		}
+
+		p, err := ReadPacket(bytes.NewReader(bin))
+		require.Nil(t, err)
+		require.Equal(t, &Packet{
+			Version: 3,
+			Messages: []Message{
+				{TargetURI: "a", ResponseURI: "/2"},
+			},
+		}, p)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, n := range []int{3, 20, len(packetBinary) - 1} {
+			_, err := ReadPacket(bytes.NewReader(packetBinary[:n]))
+			require.Equal(t, io.ErrUnexpectedEOF, err)
+		}
+	})
+}
+
+func TestWritePacket(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+
+	err := WritePacket(buf, packet)
+	require.Nil(t, err)
+	require.Equal(t, packetBinary, buf.Bytes())
+}
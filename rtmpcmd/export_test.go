@@ -0,0 +1,243 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmpcmd
+
+import (
+	"github.com/yutopp/go-amf0"
+)
+
+type testCase struct {
+	Name    string
+	Command Command
+	Binary  []byte
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+var testCases = []testCase{
+	{
+		Name: "connect",
+		Command: &Connect{
+			TransactionID: 1,
+			CommandObject: ConnectObject{
+				App:           "live",
+				Type:          "nonprivate",
+				FlashVer:      "FMLE/3.0",
+				TcURL:         "rtmp://localhost/live",
+				Capabilities:  15,
+				AudioCodecs:   3191,
+				VideoCodecs:   252,
+				VideoFunction: 1,
+			},
+		},
+		Binary: connectBinary,
+	},
+	{
+		Name: "createStream",
+		Command: &CreateStream{
+			TransactionID: 2,
+		},
+		Binary: createStreamBinary,
+	},
+	{
+		Name: "publish",
+		Command: &Publish{
+			TransactionID:  5,
+			PublishingName: "stream",
+			PublishingType: "live",
+		},
+		Binary: publishBinary,
+	},
+	{
+		Name: "play",
+		Command: &Play{
+			TransactionID: 4,
+			StreamName:    "stream",
+			Start:         float64Ptr(-2),
+		},
+		Binary: playBinary,
+	},
+	{
+		Name: "deleteStream",
+		Command: &DeleteStream{
+			TransactionID: 6,
+			StreamID:      1,
+		},
+		Binary: deleteStreamBinary,
+	},
+	{
+		Name: "_result of connect",
+		Command: &Result{
+			TransactionID: 1,
+			CommandObject: map[string]interface{}{
+				"capabilities": float64(31),
+				"fmsVer":       "FMS/3,0,1,123",
+			},
+			Info: map[string]interface{}{
+				"code":  "NetConnection.Connect.Success",
+				"level": "status",
+			},
+		},
+		Binary: connectResultBinary,
+	},
+	{
+		Name: "_result of createStream",
+		Command: &Result{
+			TransactionID: 2,
+			Info:          float64(1),
+		},
+		Binary: createStreamResultBinary,
+	},
+	{
+		Name: "_error",
+		Command: &Error{
+			TransactionID: 3,
+			Info: map[string]interface{}{
+				"code":  "NetStream.Publish.BadName",
+				"level": "error",
+			},
+		},
+		Binary: errorBinary,
+	},
+	{
+		Name: "onStatus",
+		Command: &OnStatus{
+			Info: StatusInfo{
+				Level:       StatusLevelStatus,
+				Code:        "NetStream.Publish.Start",
+				Description: "Start publishing.",
+			},
+		},
+		Binary: onStatusBinary,
+	},
+	{
+		Name: "unknown",
+		Command: &Unknown{
+			Name:          "FCPublish",
+			TransactionID: 3,
+			Args: []amf0.RawMessage{
+				{0x05},
+				{0x02, 0x00, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d},
+			},
+		},
+		Binary: unknownBinary,
+	},
+}
+
+var connectBinary = []byte{
+	0x02, 0x00, 0x07, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, // String(connect)
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+	0x03,                         // Object Marker
+	0x00, 0x03, 0x61, 0x70, 0x70, //   Key(app)
+	0x02, 0x00, 0x04, 0x6c, 0x69, 0x76, 0x65, //   String(live)
+	0x00, 0x04, 0x74, 0x79, 0x70, 0x65, //   Key(type)
+	0x02, 0x00, 0x0a, 0x6e, 0x6f, 0x6e, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, //   String(nonprivate)
+	0x00, 0x08, 0x66, 0x6c, 0x61, 0x73, 0x68, 0x56, 0x65, 0x72, //   Key(flashVer)
+	0x02, 0x00, 0x08, 0x46, 0x4d, 0x4c, 0x45, 0x2f, 0x33, 0x2e, 0x30, //   String(FMLE/3.0)
+	0x00, 0x05, 0x74, 0x63, 0x55, 0x72, 0x6c, //   Key(tcUrl)
+	0x02, 0x00, 0x15, 0x72, 0x74, 0x6d, 0x70, 0x3a, 0x2f, 0x2f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x68, 0x6f, 0x73, 0x74, 0x2f, 0x6c, 0x69, 0x76, 0x65, //   String(rtmp://localhost/live)
+	0x00, 0x04, 0x66, 0x70, 0x61, 0x64, //   Key(fpad)
+	0x01, 0x00, //   Boolean(false)
+	0x00, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, //   Key(capabilities)
+	0x00, 0x40, 0x2e, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(15)
+	0x00, 0x0b, 0x61, 0x75, 0x64, 0x69, 0x6f, 0x43, 0x6f, 0x64, 0x65, 0x63, 0x73, //   Key(audioCodecs)
+	0x00, 0x40, 0xa8, 0xee, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(3191)
+	0x00, 0x0b, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x43, 0x6f, 0x64, 0x65, 0x63, 0x73, //   Key(videoCodecs)
+	0x00, 0x40, 0x6f, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(252)
+	0x00, 0x0d, 0x76, 0x69, 0x64, 0x65, 0x6f, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, //   Key(videoFunction)
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(1)
+	0x00, 0x00, 0x09, //   End
+}
+
+var createStreamBinary = []byte{
+	0x02, 0x00, 0x0c, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, // String(createStream)
+	0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(2)
+	0x05, // Null
+}
+
+var publishBinary = []byte{
+	0x02, 0x00, 0x07, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, // String(publish)
+	0x00, 0x40, 0x14, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(5)
+	0x05,                                                 // Null
+	0x02, 0x00, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, // String(stream)
+	0x02, 0x00, 0x04, 0x6c, 0x69, 0x76, 0x65, // String(live)
+}
+
+var playBinary = []byte{
+	0x02, 0x00, 0x04, 0x70, 0x6c, 0x61, 0x79, // String(play)
+	0x00, 0x40, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(4)
+	0x05,                                                 // Null
+	0x02, 0x00, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, // String(stream)
+	0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(-2)
+}
+
+var deleteStreamBinary = []byte{
+	0x02, 0x00, 0x0c, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, // String(deleteStream)
+	0x00, 0x40, 0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(6)
+	0x05,                                                 // Null
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+}
+
+var connectResultBinary = []byte{
+	0x02, 0x00, 0x07, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, // String(_result)
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+	0x03,                                                                               // Object Marker
+	0x00, 0x0c, 0x63, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, //   Key(capabilities)
+	0x00, 0x40, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(31)
+	0x00, 0x06, 0x66, 0x6d, 0x73, 0x56, 0x65, 0x72, //   Key(fmsVer)
+	0x02, 0x00, 0x0d, 0x46, 0x4d, 0x53, 0x2f, 0x33, 0x2c, 0x30, 0x2c, 0x31, 0x2c, 0x31, 0x32, 0x33, //   String(FMS/3,0,1,123)
+	0x00, 0x00, 0x09, //   End
+	0x03,                               // Object Marker
+	0x00, 0x04, 0x63, 0x6f, 0x64, 0x65, //   Key(code)
+	0x02, 0x00, 0x1d, 0x4e, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x2e, 0x53, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, //   String(NetConnection.Connect.Success)
+	0x00, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, //   Key(level)
+	0x02, 0x00, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, //   String(status)
+	0x00, 0x00, 0x09, //   End
+}
+
+var createStreamResultBinary = []byte{
+	0x02, 0x00, 0x07, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, // String(_result)
+	0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(2)
+	0x05,                                                 // Null
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+}
+
+var errorBinary = []byte{
+	0x02, 0x00, 0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, // String(_error)
+	0x00, 0x40, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(3)
+	0x05,                               // Null
+	0x03,                               // Object Marker
+	0x00, 0x04, 0x63, 0x6f, 0x64, 0x65, //   Key(code)
+	0x02, 0x00, 0x19, 0x4e, 0x65, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x2e, 0x42, 0x61, 0x64, 0x4e, 0x61, 0x6d, 0x65, //   String(NetStream.Publish.BadName)
+	0x00, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, //   Key(level)
+	0x02, 0x00, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, //   String(error)
+	0x00, 0x00, 0x09, //   End
+}
+
+var onStatusBinary = []byte{
+	0x02, 0x00, 0x08, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, // String(onStatus)
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(0)
+	0x05,                                     // Null
+	0x03,                                     // Object Marker
+	0x00, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, //   Key(level)
+	0x02, 0x00, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, //   String(status)
+	0x00, 0x04, 0x63, 0x6f, 0x64, 0x65, //   Key(code)
+	0x02, 0x00, 0x17, 0x4e, 0x65, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x2e, 0x53, 0x74, 0x61, 0x72, 0x74, //   String(NetStream.Publish.Start)
+	0x00, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, //   Key(description)
+	0x02, 0x00, 0x11, 0x53, 0x74, 0x61, 0x72, 0x74, 0x20, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x69, 0x6e, 0x67, 0x2e, //   String(Start publishing.)
+	0x00, 0x00, 0x09, //   End
+}
+
+var unknownBinary = []byte{
+	0x02, 0x00, 0x09, 0x46, 0x43, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, // String(FCPublish)
+	0x00, 0x40, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(3)
+	0x05,                                                 // Null
+	0x02, 0x00, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, // String(stream)
+}
@@ -0,0 +1,259 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package rtmpcmd Typed RTMP command messages encoded in AMF0
+package rtmpcmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/yutopp/go-amf0"
+)
+
+const (
+	// NameConnect A name of connect commands
+	NameConnect = "connect"
+	// NameCreateStream A name of createStream commands
+	NameCreateStream = "createStream"
+	// NamePublish A name of publish commands
+	NamePublish = "publish"
+	// NamePlay A name of play commands
+	NamePlay = "play"
+	// NameDeleteStream A name of deleteStream commands
+	NameDeleteStream = "deleteStream"
+	// NameResult A name of _result commands
+	NameResult = "_result"
+	// NameError A name of _error commands
+	NameError = "_error"
+	// NameOnStatus A name of onStatus commands
+	NameOnStatus = "onStatus"
+)
+
+// Command An RTMP command message. Fields of the struct are encoded in order after the command name.
+type Command interface {
+	CommandName() string
+}
+
+// Connect A connect command which is sent by clients to connect to applications
+type Connect struct {
+	TransactionID float64
+	CommandObject ConnectObject
+	Args          []interface{} `amf0:",rest"` // Optional user arguments
+}
+
+// CommandName Implements Command
+func (c *Connect) CommandName() string {
+	return NameConnect
+}
+
+// ConnectObject A command object of connect commands. Properties are encoded in the order of fields.
+type ConnectObject struct {
+	App            string  `amf0:"app,omitempty"`
+	Type           string  `amf0:"type,omitempty"`
+	FlashVer       string  `amf0:"flashVer,omitempty"`
+	SwfURL         string  `amf0:"swfUrl,omitempty"`
+	TcURL          string  `amf0:"tcUrl,omitempty"`
+	Fpad           bool    `amf0:"fpad"`
+	Capabilities   float64 `amf0:"capabilities,omitempty"`
+	AudioCodecs    float64 `amf0:"audioCodecs,omitempty"`
+	VideoCodecs    float64 `amf0:"videoCodecs,omitempty"`
+	VideoFunction  float64 `amf0:"videoFunction,omitempty"`
+	PageURL        string  `amf0:"pageUrl,omitempty"`
+	ObjectEncoding float64 `amf0:"objectEncoding,omitempty"`
+}
+
+// CreateStream A createStream command which is sent by clients to create message streams
+type CreateStream struct {
+	TransactionID float64
+	CommandObject interface{} // Usually Null
+}
+
+// CommandName Implements Command
+func (c *CreateStream) CommandName() string {
+	return NameCreateStream
+}
+
+// Publish A publish command which is sent by clients to publish streams
+type Publish struct {
+	TransactionID  float64
+	CommandObject  interface{} // Usually Null
+	PublishingName string
+	PublishingType string // "live", "record" or "append"
+}
+
+// CommandName Implements Command
+func (c *Publish) CommandName() string {
+	return NamePublish
+}
+
+// Play A play command which is sent by clients to play streams
+type Play struct {
+	TransactionID float64
+	CommandObject interface{} // Usually Null
+	StreamName    string
+	Start         *float64 `amf0:",omitempty"`
+	Duration      *float64 `amf0:",omitempty"`
+	Reset         *bool    `amf0:",omitempty"`
+}
+
+// CommandName Implements Command
+func (c *Play) CommandName() string {
+	return NamePlay
+}
+
+// DeleteStream A deleteStream command which is sent by clients to delete message streams
+type DeleteStream struct {
+	TransactionID float64
+	CommandObject interface{} // Usually Null
+	StreamID      float64
+}
+
+// CommandName Implements Command
+func (c *DeleteStream) CommandName() string {
+	return NameDeleteStream
+}
+
+// Result A _result command which is a successful response to the command which has the same transaction ID
+type Result struct {
+	TransactionID float64
+	CommandObject interface{} // Properties for connect commands, otherwise usually Null
+	Info          interface{} `amf0:",omitempty"` // Information objects for connect commands, stream IDs for createStream commands
+}
+
+// CommandName Implements Command
+func (c *Result) CommandName() string {
+	return NameResult
+}
+
+// Error An _error command which is a failure response to the command which has the same transaction ID
+type Error struct {
+	TransactionID float64
+	CommandObject interface{} // Usually Null
+	Info          interface{} `amf0:",omitempty"` // Information objects which describe errors
+}
+
+// CommandName Implements Command
+func (c *Error) CommandName() string {
+	return NameError
+}
+
+// OnStatus An onStatus command which is sent by servers to notify statuses of streams
+type OnStatus struct {
+	TransactionID float64     // Always 0
+	CommandObject interface{} // Always Null
+	Info          StatusInfo
+}
+
+// CommandName Implements Command
+func (c *OnStatus) CommandName() string {
+	return NameOnStatus
+}
+
+const (
+	// StatusLevelStatus A level of status notifications
+	StatusLevelStatus = "status"
+	// StatusLevelWarning A level of warnings
+	StatusLevelWarning = "warning"
+	// StatusLevelError A level of errors
+	StatusLevelError = "error"
+)
+
+// StatusInfo An information object of onStatus commands
+type StatusInfo struct {
+	Level       string `amf0:"level"`
+	Code        string `amf0:"code"`
+	Description string `amf0:"description,omitempty"`
+}
+
+// Unknown A command which is not registered to the dispatcher. Arguments are kept as raw values.
+type Unknown struct {
+	Name          string `amf0:"-"`
+	TransactionID float64
+	Args          []amf0.RawMessage `amf0:",rest"`
+}
+
+// CommandName Implements Command
+func (c *Unknown) CommandName() string {
+	return c.Name
+}
+
+// Dispatcher Decodes command messages into typed commands by their names
+type Dispatcher struct {
+	factories map[string]func() Command
+}
+
+// NewDispatcher Create a new instance of Dispatcher which knows commands defined in this package
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		factories: make(map[string]func() Command),
+	}
+
+	d.Register(NameConnect, func() Command { return &Connect{} })
+	d.Register(NameCreateStream, func() Command { return &CreateStream{} })
+	d.Register(NamePublish, func() Command { return &Publish{} })
+	d.Register(NamePlay, func() Command { return &Play{} })
+	d.Register(NameDeleteStream, func() Command { return &DeleteStream{} })
+	d.Register(NameResult, func() Command { return &Result{} })
+	d.Register(NameError, func() Command { return &Error{} })
+	d.Register(NameOnStatus, func() Command { return &OnStatus{} })
+
+	return d
+}
+
+// Register Register a factory of the command which has the name. The factory must return a pointer to a struct.
+func (d *Dispatcher) Register(name string, factory func() Command) {
+	d.factories[name] = factory
+}
+
+// Decode Read a command message and decode it into the registered command. Unregistered commands are decoded into Unknown.
+func (d *Dispatcher) Decode(dec *amf0.Decoder) (Command, error) {
+	marker, err := dec.PeekMarker()
+	if err != nil {
+		return nil, err
+	}
+	if marker != amf0.MarkerString {
+		return nil, fmt.Errorf("command name must be a string: Marker = %+v", marker)
+	}
+
+	var name string
+	if err := dec.Decode(&name); err != nil {
+		return nil, err
+	}
+
+	var cmd Command
+	if factory, ok := d.factories[name]; ok {
+		cmd = factory()
+	} else {
+		cmd = &Unknown{Name: name}
+	}
+
+	if err := dec.DecodeSequence(cmd); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF // Transaction IDs are required
+		}
+		return nil, err
+	}
+
+	return cmd, nil
+}
+
+var defaultDispatcher = NewDispatcher()
+
+// Decode Read a command message and decode it into the command defined in this package
+func Decode(dec *amf0.Decoder) (Command, error) {
+	return defaultDispatcher.Decode(dec)
+}
+
+// Encode Write the command message
+func Encode(enc *amf0.Encoder, cmd Command) error {
+	if err := enc.Encode(cmd.CommandName()); err != nil {
+		return err
+	}
+
+	return enc.EncodeSequence(cmd)
+}
@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package rtmpcmd
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func TestDecode(t *testing.T) {
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			r := bytes.NewReader(tc.Binary)
+			dec := amf0.NewDecoder(r)
+
+			cmd, err := Decode(dec)
+			require.Nil(t, err)
+			require.Equal(t, tc.Command, cmd)
+			require.Equal(t, 0, r.Len()) // Assure that all bytes are consumed
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range testCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBuffer([]byte{})
+			enc := amf0.NewEncoder(buf, amf0.WithSortedKeys())
+
+			err := Encode(enc, tc.Command)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+// sampleReleaseStream A command which is not defined in the package
+type sampleReleaseStream struct {
+	TransactionID float64
+	CommandObject interface{}
+	StreamName    string
+}
+
+func (c *sampleReleaseStream) CommandName() string {
+	return "releaseStream"
+}
+
+func TestDispatcherRegister(t *testing.T) {
+	d := NewDispatcher()
+	d.Register("releaseStream", func() Command { return &sampleReleaseStream{} })
+
+	cmd := &sampleReleaseStream{TransactionID: 2, StreamName: "stream"}
+
+	buf := bytes.NewBuffer([]byte{})
+	err := Encode(amf0.NewEncoder(buf), cmd)
+	require.Nil(t, err)
+
+	actual, err := d.Decode(amf0.NewDecoder(buf))
+	require.Nil(t, err)
+	require.Equal(t, cmd, actual)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Run("EOF", func(t *testing.T) {
+		_, err := Decode(amf0.NewDecoder(bytes.NewReader([]byte{})))
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("no transaction ID", func(t *testing.T) {
+		bin := createStreamBinary[:15] // Only the name
+		_, err := Decode(amf0.NewDecoder(bytes.NewReader(bin)))
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+
+	t.Run("name is not string", func(t *testing.T) {
+		bin := createStreamBinary[15:] // Starts with the transaction ID
+		_, err := Decode(amf0.NewDecoder(bytes.NewReader(bin)))
+		require.Error(t, err)
+	})
+}
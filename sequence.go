@@ -0,0 +1,169 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// DecodeSequence Decode consecutive values into fields of the struct in order, such as arguments of RTMP commands.
+// The last field tagged with `amf0:",rest"` gathers trailing values until the reader reaches EOF into the slice.
+// Fields tagged with omitempty are optional, thus they are left as is if the reader reaches EOF before them.
+// io.EOF is returned if the reader reaches EOF before the first value.
+func (dec *Decoder) DecodeSequence(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("not a non-nil pointer to struct: %T", v)
+	}
+	rv = rv.Elem()
+
+	fields := cachedTypeFields(rv.Type()).List
+	for i := range fields {
+		f := &fields[i]
+		if f.Rest && i != len(fields)-1 {
+			return fmt.Errorf("rest field must be the last field: %s", f.Name)
+		}
+
+		fv, err := fieldByIndexAlloc(rv, f.Index)
+		if err != nil {
+			return err
+		}
+
+		if f.Rest {
+			return dec.decodeRest(fv)
+		}
+
+		if _, err := dec.PeekMarker(); err != nil {
+			if err != io.EOF {
+				return err
+			}
+			if i == 0 {
+				return io.EOF
+			}
+			if f.OmitEmpty {
+				continue
+			}
+			return io.ErrUnexpectedEOF
+		}
+
+		if f.AsString {
+			if err := dec.beginValue(); err != nil {
+				return err
+			}
+			if err := dec.decodeStringified(fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := dec.Decode(fv.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeRest Decodes values until EOF into the slice
+func (dec *Decoder) decodeRest(rv reflect.Value) error {
+	for {
+		if _, err := dec.PeekMarker(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		elem := reflect.New(rv.Type().Elem())
+		if err := dec.Decode(elem.Interface()); err != nil {
+			return wrapEOF(err) // The value is truncated because the marker is peeked
+		}
+		rv.Set(reflect.Append(rv, elem.Elem()))
+	}
+}
+
+// DecodeAll Decode all values until the reader reaches EOF
+func (dec *Decoder) DecodeAll() ([]interface{}, error) {
+	var values []interface{}
+	if err := dec.decodeRest(reflect.ValueOf(&values).Elem()); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// EncodeSequence Encode fields of the struct as consecutive values in order. It is the inverse of Decoder.DecodeSequence.
+// Fields tagged with omitempty are omitted only if they and all of the following fields are empty, so as not to shift positions.
+func (enc *Encoder) EncodeSequence(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("not a struct or a pointer to struct: %T", v)
+	}
+
+	fields := cachedTypeFields(rv.Type()).List
+
+	// Find the last field which must be written
+	last := -1
+	for i := range fields {
+		f := &fields[i]
+		if f.Rest && i != len(fields)-1 {
+			return fmt.Errorf("rest field must be the last field: %s", f.Name)
+		}
+
+		fv, ok := fieldByIndex(rv, f.Index)
+		if !ok {
+			fv = reflect.Value{} // Written as Null
+		}
+		if (f.OmitEmpty || f.Rest) && (!fv.IsValid() || isEmptyValue(fv)) {
+			continue
+		}
+		last = i
+	}
+
+	for i := 0; i <= last; i++ {
+		f := &fields[i]
+
+		fv, ok := fieldByIndex(rv, f.Index)
+		if !ok {
+			if err := enc.Encode(nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if f.Rest {
+			for j := 0; j < fv.Len(); j++ {
+				if err := enc.Encode(fv.Index(j).Interface()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if f.AsString {
+			if err := enc.beginValue(); err != nil {
+				return err
+			}
+			if err := enc.encodeStringified(fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := enc.Encode(fv.Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
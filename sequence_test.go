@@ -0,0 +1,227 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sampleCommand struct {
+	Name          string
+	TransactionID int
+	Object        interface{}
+	Args          []interface{} `amf0:",rest"`
+}
+
+var sampleCommandBinary = func() []byte {
+	bin := []byte{
+		0x02, 0x00, 0x07, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, // String(connect)
+		0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+	}
+	bin = append(bin, orderedObjectTest.Binary...)
+	bin = append(bin, 0x02, 0x00, 0x01, 0x61) // String(a)
+	bin = append(bin, 0x05)                   // Null
+
+	return bin
+}()
+
+func TestDecodeSequence(t *testing.T) {
+	t.Run("rest", func(t *testing.T) {
+		r := bytes.NewReader(sampleCommandBinary)
+		dec := NewDecoder(r, WithOrderedObjects())
+
+		var cmd sampleCommand
+		err := dec.DecodeSequence(&cmd)
+		require.Nil(t, err)
+		require.Equal(t, sampleCommand{
+			Name:          "connect",
+			TransactionID: 1,
+			Object:        orderedObjectTest.Value,
+			Args:          []interface{}{"a", nil},
+		}, cmd)
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		type command struct {
+			Name          string
+			TransactionID int
+			Object        interface{}
+			StreamName    string `amf0:",omitempty"`
+		}
+
+		r := bytes.NewReader(sampleCommandBinary[:19])
+		dec := NewDecoder(r)
+
+		cmd := command{Object: "x"}
+		err := dec.DecodeSequence(&cmd)
+		require.Equal(t, io.ErrUnexpectedEOF, err) // Object is required
+
+		type optionalCommand struct {
+			Name          string
+			TransactionID int
+			Object        interface{} `amf0:",omitempty"`
+			StreamName    string      `amf0:",omitempty"`
+		}
+
+		r = bytes.NewReader(sampleCommandBinary[:19])
+		dec = NewDecoder(r)
+
+		var optCmd optionalCommand
+		err = dec.DecodeSequence(&optCmd)
+		require.Nil(t, err)
+		require.Equal(t, optionalCommand{Name: "connect", TransactionID: 1}, optCmd)
+	})
+
+	t.Run("EOF", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader([]byte{}))
+
+		var cmd sampleCommand
+		err := dec.DecodeSequence(&cmd)
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("rest is not last", func(t *testing.T) {
+		type command struct {
+			Args []interface{} `amf0:",rest"`
+			Name string
+		}
+
+		dec := NewDecoder(bytes.NewReader(sampleCommandBinary))
+
+		var cmd command
+		err := dec.DecodeSequence(&cmd)
+		require.Error(t, err)
+	})
+
+	t.Run("not pointer to struct", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(sampleCommandBinary))
+
+		var cmd sampleCommand
+		err := dec.DecodeSequence(cmd)
+		require.Error(t, err)
+
+		err = dec.DecodeSequence(nil)
+		require.Error(t, err)
+	})
+}
+
+func TestEncodeSequence(t *testing.T) {
+	t.Run("rest", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.EncodeSequence(&sampleCommand{
+			Name:          "connect",
+			TransactionID: 1,
+			Object:        orderedObjectTest.Value,
+			Args:          []interface{}{"a", nil},
+		})
+		require.Nil(t, err)
+		require.Equal(t, sampleCommandBinary, buf.Bytes())
+	})
+
+	t.Run("omitempty", func(t *testing.T) {
+		type command struct {
+			Name          string
+			TransactionID int         `amf0:",omitempty"`
+			Object        interface{} `amf0:",omitempty"`
+			StreamName    string      `amf0:",omitempty"`
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.EncodeSequence(command{Name: "a", Object: nil, StreamName: ""})
+		require.Nil(t, err)
+		require.Equal(t, []byte{0x02, 0x00, 0x01, 0x61}, buf.Bytes())
+
+		buf.Reset()
+		err = enc.EncodeSequence(command{Name: "a", StreamName: "b"})
+		require.Nil(t, err)
+		require.Equal(t, []byte{
+			0x02, 0x00, 0x01, 0x61, // String(a)
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(0)
+			0x05,                   // Null
+			0x02, 0x00, 0x01, 0x62, // String(b)
+		}, buf.Bytes())
+	})
+
+	t.Run("not struct", func(t *testing.T) {
+		enc := NewEncoder(bytes.NewBuffer([]byte{}))
+
+		err := enc.EncodeSequence(1)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeAll(t *testing.T) {
+	r := bytes.NewReader(sampleCommandBinary)
+	dec := NewDecoder(r, WithOrderedObjects())
+
+	values, err := dec.DecodeAll()
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"connect", float64(1), orderedObjectTest.Value, "a", nil}, values)
+
+	r = bytes.NewReader(sampleCommandBinary[:len(sampleCommandBinary)-2])
+	dec = NewDecoder(r)
+
+	_, err = dec.DecodeAll()
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestSequenceStringifiedInArray(t *testing.T) {
+	type args struct {
+		N    int    `amf0:"n,string"`
+		Name string `amf0:"name"`
+	}
+
+	bin := []byte{
+		0x0a, 0x00, 0x00, 0x00, 0x02, // StrictArray(length 2)
+		0x02, 0x00, 0x01, 0x31, //       - String(1)
+		0x02, 0x00, 0x01, 0x61, //       - String(a)
+	}
+
+	t.Run("encode", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		err := enc.BeginStrictArray(2)
+		require.Nil(t, err)
+		err = enc.EncodeSequence(args{N: 1, Name: "a"})
+		require.Nil(t, err)
+		err = enc.EndArray()
+		require.Nil(t, err)
+		require.Equal(t, bin, buf.Bytes())
+
+		err = enc.BeginStrictArray(1)
+		require.Nil(t, err)
+		err = enc.EncodeSequence(args{N: 1, Name: "a"})
+		require.IsType(t, &StateError{}, err) // The stringified value consumes the only slot
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		dec := NewDecoder(bytes.NewReader(bin))
+
+		tok, err := dec.Token()
+		require.Nil(t, err)
+		require.Equal(t, TokenStartArray, tok.Kind)
+
+		var v args
+		err = dec.DecodeSequence(&v)
+		require.Nil(t, err)
+		require.Equal(t, args{N: 1, Name: "a"}, v)
+
+		tok, err = dec.Token()
+		require.Nil(t, err)
+		require.Equal(t, TokenEndArray, tok.Kind)
+	})
+}
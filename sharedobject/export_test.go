@@ -0,0 +1,64 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package sharedobject
+
+import (
+	"github.com/yutopp/go-amf0"
+)
+
+var message = &Message{
+	Name:    "board",
+	Version: 3,
+	Flags:   2,
+	Events: []Event{
+		&Use{},
+		&Change{
+			Properties: amf0.OrderedObject{
+				{Key: "x", Value: float64(1)},
+				{Key: "color", Value: "red"},
+			},
+		},
+		&Success{Name: "x"},
+		&SendMessage{
+			Method: "draw",
+			Args:   []interface{}{float64(1), "pen"},
+		},
+		&Status{Code: "SharedObject.NoWriteAccess", Level: "error"},
+		&Clear{},
+		&Remove{Name: "x"},
+		&UseSuccess{},
+		&RawEvent{Type: 0x20, Data: []byte{0x01, 0x02}},
+	},
+}
+
+var messageBinary = []byte{
+	0x00, 0x05, 0x62, 0x6f, 0x61, 0x72, 0x64, // Name(board)
+	0x00, 0x00, 0x00, 0x03, // Version(3)
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, // Flags(2)
+	0x01, 0x00, 0x00, 0x00, 0x00, // Use, Length(0)
+	0x04, 0x00, 0x00, 0x00, 0x19, // Change, Length(25)
+	0x00, 0x01, 0x78, //   Key(x)
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(1)
+	0x00, 0x05, 0x63, 0x6f, 0x6c, 0x6f, 0x72, //   Key(color)
+	0x02, 0x00, 0x03, 0x72, 0x65, 0x64, //   String(red)
+	0x05, 0x00, 0x00, 0x00, 0x03, // Success, Length(3)
+	0x00, 0x01, 0x78, //   Name(x)
+	0x06, 0x00, 0x00, 0x00, 0x16, // Send Message, Length(22)
+	0x02, 0x00, 0x04, 0x64, 0x72, 0x61, 0x77, //   String(draw)
+	0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //   Number(1)
+	0x02, 0x00, 0x03, 0x70, 0x65, 0x6e, //   String(pen)
+	0x07, 0x00, 0x00, 0x00, 0x23, // Status, Length(35)
+	0x00, 0x1a, 0x53, 0x68, 0x61, 0x72, 0x65, 0x64, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x2e, 0x4e, 0x6f, 0x57, 0x72, 0x69, 0x74, 0x65, 0x41, 0x63, 0x63, 0x65, 0x73, 0x73, //   Code(SharedObject.NoWriteAccess)
+	0x00, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, //   Level(error)
+	0x08, 0x00, 0x00, 0x00, 0x00, // Clear, Length(0)
+	0x09, 0x00, 0x00, 0x00, 0x03, // Remove, Length(3)
+	0x00, 0x01, 0x78, //   Name(x)
+	0x0b, 0x00, 0x00, 0x00, 0x00, // Use Success, Length(0)
+	0x20, 0x00, 0x00, 0x00, 0x02, // Unknown, Length(2)
+	0x01, 0x02, //   Data
+}
@@ -0,0 +1,475 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+// Package sharedobject Encoders and decoders of RTMP shared object messages in AMF0 (message type 19)
+package sharedobject
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/yutopp/go-amf0"
+)
+
+// EventType A type of shared object events
+type EventType uint8
+
+const (
+	// EventTypeUse A client uses the shared object
+	EventTypeUse EventType = 1
+	// EventTypeRelease A client releases the shared object
+	EventTypeRelease EventType = 2
+	// EventTypeRequestChange A client requests to change properties
+	EventTypeRequestChange EventType = 3
+	// EventTypeChange A server notifies changes of properties
+	EventTypeChange EventType = 4
+	// EventTypeSuccess A server accepts the requested change
+	EventTypeSuccess EventType = 5
+	// EventTypeSendMessage A message which is broadcast to clients
+	EventTypeSendMessage EventType = 6
+	// EventTypeStatus A server notifies an error
+	EventTypeStatus EventType = 7
+	// EventTypeClear A server clears the shared object
+	EventTypeClear EventType = 8
+	// EventTypeRemove A server removes a property
+	EventTypeRemove EventType = 9
+	// EventTypeRequestRemove A client requests to remove a property
+	EventTypeRequestRemove EventType = 10
+	// EventTypeUseSuccess A server accepts the use of the shared object
+	EventTypeUseSuccess EventType = 11
+)
+
+// Message A shared object message
+type Message struct {
+	Name    string
+	Version uint32
+	Flags   uint64 // 8 bytes of flags. 2 means persistent shared objects
+	Events  []Event
+}
+
+// Event An event of shared object messages
+type Event interface {
+	EventType() EventType
+}
+
+// Use EventTypeUse
+type Use struct{}
+
+// EventType Implements Event
+func (e *Use) EventType() EventType { return EventTypeUse }
+
+// Release EventTypeRelease
+type Release struct{}
+
+// EventType Implements Event
+func (e *Release) EventType() EventType { return EventTypeRelease }
+
+// RequestChange EventTypeRequestChange
+type RequestChange struct {
+	Properties amf0.OrderedObject
+}
+
+// EventType Implements Event
+func (e *RequestChange) EventType() EventType { return EventTypeRequestChange }
+
+// Change EventTypeChange
+type Change struct {
+	Properties amf0.OrderedObject
+}
+
+// EventType Implements Event
+func (e *Change) EventType() EventType { return EventTypeChange }
+
+// Success EventTypeSuccess
+type Success struct {
+	Name string // A name of the changed property
+}
+
+// EventType Implements Event
+func (e *Success) EventType() EventType { return EventTypeSuccess }
+
+// SendMessage EventTypeSendMessage
+type SendMessage struct {
+	Method string
+	Args   []interface{}
+}
+
+// EventType Implements Event
+func (e *SendMessage) EventType() EventType { return EventTypeSendMessage }
+
+// Status EventTypeStatus
+type Status struct {
+	Code  string
+	Level string
+}
+
+// EventType Implements Event
+func (e *Status) EventType() EventType { return EventTypeStatus }
+
+// Clear EventTypeClear
+type Clear struct{}
+
+// EventType Implements Event
+func (e *Clear) EventType() EventType { return EventTypeClear }
+
+// Remove EventTypeRemove
+type Remove struct {
+	Name string // A name of the removed property
+}
+
+// EventType Implements Event
+func (e *Remove) EventType() EventType { return EventTypeRemove }
+
+// RequestRemove EventTypeRequestRemove
+type RequestRemove struct {
+	Name string // A name of the property to remove
+}
+
+// EventType Implements Event
+func (e *RequestRemove) EventType() EventType { return EventTypeRequestRemove }
+
+// UseSuccess EventTypeUseSuccess
+type UseSuccess struct{}
+
+// EventType Implements Event
+func (e *UseSuccess) EventType() EventType { return EventTypeUseSuccess }
+
+// RawEvent An event which type is unknown. Data is kept as is.
+type RawEvent struct {
+	Type EventType
+	Data []byte
+}
+
+// EventType Implements Event
+func (e *RawEvent) EventType() EventType { return e.Type }
+
+// Decode Read a shared object message until the reader reaches EOF.
+// Options are applied to decoders of AMF0 values in events. MaxBytes is bounded by the length of each event not to allocate values beyond it.
+func Decode(r io.Reader, opts ...amf0.DecoderOption) (*Message, error) {
+	name, err := readUTF8(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Version uint32
+		Flags   uint64
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, wrapEOF(err)
+	}
+
+	msg := &Message{
+		Name:    name,
+		Version: header.Version,
+		Flags:   header.Flags,
+	}
+	for {
+		var header eventHeader
+		if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+			if err == io.EOF {
+				break // No more events
+			}
+			return nil, wrapEOF(err)
+		}
+
+		// EOF in data of the event means that the event is truncated
+		ev, err := decodeEvent(r, &header, opts)
+		if err != nil {
+			return nil, wrapEOF(err)
+		}
+		msg.Events = append(msg.Events, ev)
+	}
+
+	return msg, nil
+}
+
+// eventHeader A header which precedes data of each event
+type eventHeader struct {
+	Type   EventType
+	Length uint32
+}
+
+func decodeEvent(r io.Reader, header *eventHeader, opts []amf0.DecoderOption) (Event, error) {
+	// Read data through LimitReader not to allocate a large buffer by a broken length
+	data, err := io.ReadAll(io.LimitReader(r, int64(header.Length)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != int(header.Length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	br := bytes.NewReader(data)
+	dec := newEventDecoder(br, len(data), opts)
+
+	var ev Event
+	switch header.Type {
+	case EventTypeUse:
+		ev = &Use{}
+	case EventTypeRelease:
+		ev = &Release{}
+	case EventTypeClear:
+		ev = &Clear{}
+	case EventTypeUseSuccess:
+		ev = &UseSuccess{}
+
+	case EventTypeRequestChange:
+		props, err := decodeProperties(dec)
+		if err != nil {
+			return nil, err
+		}
+		ev = &RequestChange{Properties: props}
+
+	case EventTypeChange:
+		props, err := decodeProperties(dec)
+		if err != nil {
+			return nil, err
+		}
+		ev = &Change{Properties: props}
+
+	case EventTypeSuccess, EventTypeRemove, EventTypeRequestRemove:
+		name, err := readUTF8(dec)
+		if err != nil {
+			return nil, err
+		}
+		switch header.Type {
+		case EventTypeSuccess:
+			ev = &Success{Name: name}
+		case EventTypeRemove:
+			ev = &Remove{Name: name}
+		default:
+			ev = &RequestRemove{Name: name}
+		}
+
+	case EventTypeSendMessage:
+		e := &SendMessage{}
+		if err := dec.Decode(&e.Method); err != nil {
+			return nil, wrapEOF(err)
+		}
+		args, err := dec.DecodeAll()
+		if err != nil {
+			return nil, wrapEOF(err)
+		}
+		e.Args = args
+		ev = e
+
+	case EventTypeStatus:
+		e := &Status{}
+		if e.Code, err = readUTF8(dec); err != nil {
+			return nil, err
+		}
+		if e.Level, err = readUTF8(dec); err != nil {
+			return nil, err
+		}
+		ev = e
+
+	default:
+		return &RawEvent{Type: header.Type, Data: data}, nil
+	}
+
+	if br.Len() != 0 {
+		return nil, fmt.Errorf("data of the event remains: Type = %d, Length = %d", header.Type, br.Len())
+	}
+
+	return ev, nil
+}
+
+// newEventDecoder Creates a decoder of data of the event which MaxBytes is bounded by the length of data
+func newEventDecoder(r io.Reader, length int, opts []amf0.DecoderOption) *amf0.Decoder {
+	dec := amf0.NewDecoder(r, opts...)
+
+	// One more byte is allowed to detect the end of data by EOF
+	limits := dec.Limits()
+	if max := int64(length) + 1; limits.MaxBytes == 0 || limits.MaxBytes > max {
+		limits.MaxBytes = max
+	}
+	amf0.WithDecoderOptions(limits)(dec)
+
+	return dec
+}
+
+// decodeProperties Decodes pairs of property names and AMF0 values until the end of data
+func decodeProperties(dec *amf0.Decoder) (amf0.OrderedObject, error) {
+	props := amf0.OrderedObject{}
+	for {
+		name, err := readUTF8(dec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, wrapEOF(err)
+		}
+
+		props = append(props, amf0.KeyValue{
+			Key:   name,
+			Value: value,
+		})
+	}
+
+	return props, nil
+}
+
+// Encode Write the shared object message
+func Encode(w io.Writer, msg *Message) error {
+	if err := writeUTF8(w, msg.Name); err != nil {
+		return err
+	}
+
+	header := struct {
+		Version uint32
+		Flags   uint64
+	}{
+		Version: msg.Version,
+		Flags:   msg.Flags,
+	}
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return err
+	}
+
+	for _, ev := range msg.Events {
+		if err := encodeEvent(w, ev); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeEvent(w io.Writer, ev Event) error {
+	buf := bytes.NewBuffer([]byte{})
+	enc := amf0.NewEncoder(buf)
+
+	switch e := ev.(type) {
+	case *Use, *Release, *Clear, *UseSuccess:
+		// No data
+
+	case *RequestChange:
+		if err := encodeProperties(enc, e.Properties); err != nil {
+			return err
+		}
+
+	case *Change:
+		if err := encodeProperties(enc, e.Properties); err != nil {
+			return err
+		}
+
+	case *Success:
+		if err := writeUTF8(enc, e.Name); err != nil {
+			return err
+		}
+
+	case *Remove:
+		if err := writeUTF8(enc, e.Name); err != nil {
+			return err
+		}
+
+	case *RequestRemove:
+		if err := writeUTF8(enc, e.Name); err != nil {
+			return err
+		}
+
+	case *SendMessage:
+		if err := enc.Encode(e.Method); err != nil {
+			return err
+		}
+		for _, arg := range e.Args {
+			if err := enc.Encode(arg); err != nil {
+				return err
+			}
+		}
+
+	case *Status:
+		if err := writeUTF8(enc, e.Code); err != nil {
+			return err
+		}
+		if err := writeUTF8(enc, e.Level); err != nil {
+			return err
+		}
+
+	case *RawEvent:
+		buf.Write(e.Data)
+
+	default:
+		return fmt.Errorf("unsupported event: %T", ev)
+	}
+
+	if uint64(buf.Len()) > math.MaxUint32 {
+		return fmt.Errorf("too large event: Expected <= %d, Actual = %d", uint32(math.MaxUint32), buf.Len())
+	}
+
+	header := eventHeader{
+		Type:   ev.EventType(),
+		Length: uint32(buf.Len()),
+	}
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// encodeProperties Encodes pairs of property names and AMF0 values
+func encodeProperties(enc *amf0.Encoder, props amf0.OrderedObject) error {
+	for _, p := range props {
+		if err := writeUTF8(enc, p.Key); err != nil {
+			return err
+		}
+
+		if err := enc.Encode(p.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readUTF8 Reads a string which is prefixed by the length as u16
+func readUTF8(r io.Reader) (string, error) {
+	var l uint16
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return "", err
+	}
+
+	str := make([]byte, l)
+	if _, err := io.ReadFull(r, str); err != nil {
+		return "", wrapEOF(err)
+	}
+
+	return string(str), nil
+}
+
+// writeUTF8 Writes a string which is prefixed by the length as u16
+func writeUTF8(w io.Writer, str string) error {
+	if len(str) > math.MaxUint16 {
+		return fmt.Errorf("too long string: Expected <= %d, Actual = %d", math.MaxUint16, len(str))
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint16(len(str))); err != nil {
+		return err
+	}
+
+	_, err := w.Write([]byte(str))
+	return err
+}
+
+func wrapEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
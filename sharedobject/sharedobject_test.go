@@ -0,0 +1,122 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package sharedobject
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yutopp/go-amf0"
+)
+
+func TestDecode(t *testing.T) {
+	r := bytes.NewReader(messageBinary)
+
+	msg, err := Decode(r)
+	require.Nil(t, err)
+	require.Equal(t, message, msg)
+	require.Equal(t, 0, r.Len())
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Run("truncated header", func(t *testing.T) {
+		_, err := Decode(bytes.NewReader(messageBinary[:10]))
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+
+	t.Run("truncated event", func(t *testing.T) {
+		_, err := Decode(bytes.NewReader(messageBinary[:len(messageBinary)-1]))
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+
+	t.Run("broken length", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		require.Nil(t, Encode(buf, &Message{Name: "a"}))
+		buf.Write([]byte{0x04, 0xff, 0xff, 0xff, 0xff}) // Change, Length(4294967295)
+
+		_, err := Decode(buf)
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+
+	t.Run("truncated data followed by an event", func(t *testing.T) {
+		for _, data := range [][]byte{
+			{0x06, 0x00, 0x00, 0x00, 0x0a, 0x02, 0x00, 0x01, 0x6d, 0x0a, 0x00, 0x00, 0x00, 0x02, 0x05}, // SendMessage, Length(10), String(m), StrictArray(length 2) with 1 element
+			{0x07, 0x00, 0x00, 0x00, 0x03, 0x00, 0x01, 0x61},                                           // Status, Length(3), Code(a) without Level
+		} {
+			buf := bytes.NewBuffer([]byte{})
+			require.Nil(t, Encode(buf, &Message{Name: "a"}))
+			buf.Write(data)
+			buf.Write([]byte{0x01, 0x00, 0x00, 0x00, 0x00}) // Use, Length(0)
+
+			_, err := Decode(buf)
+			require.Equal(t, io.ErrUnexpectedEOF, err)
+		}
+	})
+
+	t.Run("huge array in data", func(t *testing.T) {
+		bin := []byte{
+			0x00, 0x01, 0x61, // Name(a)
+			0x00, 0x00, 0x00, 0x00, // Version(0)
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Flags(0)
+			0x06, 0x00, 0x00, 0x00, 0x09, // SendMessage, Length(9)
+			0x02, 0x00, 0x01, 0x6d, //       String(m)
+			0x0a, 0x00, 0xff, 0xff, 0xff, // StrictArray(length 16777215) without elements
+		}
+
+		_, err := Decode(bytes.NewReader(bin))
+		require.IsType(t, &amf0.LimitExceededError{}, err)
+		require.Equal(t, "MaxBytes", err.(*amf0.LimitExceededError).Limit)
+
+		_, err = Decode(bytes.NewReader(bin), amf0.WithDecoderOptions(amf0.DecoderOptions{MaxArrayLength: 10}))
+		require.IsType(t, &amf0.LimitExceededError{}, err)
+		require.Equal(t, "MaxArrayLength", err.(*amf0.LimitExceededError).Limit)
+	})
+
+	t.Run("remaining data", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		require.Nil(t, Encode(buf, &Message{Name: "a"}))
+		buf.Write([]byte{0x05, 0x00, 0x00, 0x00, 0x04, 0x00, 0x01, 0x78, 0x00}) // Success, Length(4)
+
+		_, err := Decode(buf)
+		require.Error(t, err)
+	})
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("message", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+
+		err := Encode(buf, message)
+		require.Nil(t, err)
+		require.Equal(t, messageBinary, buf.Bytes())
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		msg := &Message{
+			Name: "chat",
+			Events: []Event{
+				&RequestChange{
+					Properties: amf0.OrderedObject{
+						{Key: "users", Value: []interface{}{"alice", "bob"}},
+					},
+				},
+				&RequestRemove{Name: "topic"},
+				&Release{},
+			},
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+		require.Nil(t, Encode(buf, msg))
+
+		actual, err := Decode(buf)
+		require.Nil(t, err)
+		require.Equal(t, msg, actual)
+	})
+}
@@ -0,0 +1,677 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// TokenKind A kind of tokens
+type TokenKind int
+
+const (
+	// TokenValue A primitive value
+	TokenValue TokenKind = iota
+	// TokenStartObject A beginning of Object, ECMA Array or Typed Object
+	TokenStartObject
+	// TokenKey A key of a property
+	TokenKey
+	// TokenEndObject An end of Object, ECMA Array or Typed Object
+	TokenEndObject
+	// TokenStartArray A beginning of Strict Array
+	TokenStartArray
+	// TokenEndArray An end of Strict Array
+	TokenEndArray
+)
+
+// Token A token of AMF0 values which is returned by Decoder.Token
+type Token struct {
+	Kind TokenKind
+
+	// Marker A marker of the value. It is set for TokenValue, TokenStartObject and TokenStartArray
+	Marker Marker
+	// Value A primitive value of TokenValue. Types are float64, bool, string, XMLDocument, Date, Undefined,
+	// uint16 as an index of Reference and nil for Null and Unsupported
+	Value interface{}
+	// Key A key of TokenKey
+	Key string
+	// ClassName A class name of TokenStartObject for Typed Object
+	ClassName string
+	// Length An associative count of ECMA Array or a length of Strict Array
+	Length uint32
+}
+
+// tokenFrame A state of an object or an array which is opened by Token
+type tokenFrame struct {
+	isArray     bool
+	remaining   uint32 // for arrays
+	expectValue bool   // for objects
+	numKeys     int    // for objects
+	depth       int    // a nesting level of Decode calls when the frame is opened
+}
+
+// PeekMarker Returns a marker of the next value without consuming it
+func (dec *Decoder) PeekMarker() (Marker, error) {
+	if f := dec.currentFrame(); f != nil {
+		if err := f.checkValue(); err != nil {
+			return 0, err
+		}
+	}
+
+	marker, err := dec.peekU8()
+	if err != nil {
+		return 0, err
+	}
+
+	return Marker(marker), nil
+}
+
+// Token Returns the next token. Keys and ends of objects and arrays are returned as tokens while values are nested in them.
+// Values of properties and elements can also be read by Decode and Skip instead of Token.
+// Objects and arrays opened by Token cannot be referenced by values decoded later.
+func (dec *Decoder) Token() (Token, error) {
+	if f := dec.currentFrame(); f != nil {
+		if f.isArray && f.remaining == 0 {
+			dec.frames = dec.frames[:len(dec.frames)-1]
+			return Token{Kind: TokenEndArray}, nil
+		}
+
+		if !f.isArray && !f.expectValue {
+			return dec.keyToken(f)
+		}
+	}
+
+	if err := dec.beginValue(); err != nil {
+		return Token{}, err
+	}
+
+	if err := dec.limiter.CheckDepth(dec.limiter.Nesting + len(dec.frames) + 1); err != nil {
+		return Token{}, err
+	}
+
+	marker, err := dec.readU8()
+	if err != nil {
+		return Token{}, err
+	}
+
+	tok, err := dec.valueToken(Marker(marker))
+	if err != nil {
+		return Token{}, wrapEOF(err)
+	}
+
+	return tok, nil
+}
+
+func (dec *Decoder) keyToken(f *tokenFrame) (Token, error) {
+	key, err := dec.readUTF8()
+	if err != nil {
+		return Token{}, wrapEOF(err)
+	}
+
+	if key == "" {
+		marker, err := dec.readU8()
+		if err != nil {
+			return Token{}, wrapEOF(err)
+		}
+		if Marker(marker) != MarkerObjectEnd {
+			return Token{}, &DecodeError{
+				Message: "Not ended with object-end",
+			}
+		}
+
+		dec.frames = dec.frames[:len(dec.frames)-1]
+		return Token{Kind: TokenEndObject}, nil
+	}
+
+	f.numKeys++
+	if err := dec.limiter.CheckObjectKeys(f.numKeys); err != nil {
+		return Token{}, err
+	}
+
+	f.expectValue = true
+	return Token{Kind: TokenKey, Key: key}, nil
+}
+
+func (dec *Decoder) valueToken(marker Marker) (Token, error) {
+	tok := Token{
+		Kind:   TokenValue,
+		Marker: marker,
+	}
+
+	var err error
+	switch marker {
+	case MarkerNumber:
+		tok.Value, err = dec.readDouble()
+
+	case MarkerBoolean:
+		var b uint8
+		b, err = dec.readU8()
+		tok.Value = b != 0
+
+	case MarkerString:
+		tok.Value, err = dec.readUTF8()
+
+	case MarkerLongString:
+		tok.Value, err = dec.readUTF8Long()
+
+	case MarkerXMLDocument:
+		var s string
+		s, err = dec.readUTF8Long()
+		tok.Value = XMLDocument(s)
+
+	case MarkerNull, MarkerUnsupported:
+		// No payloads
+
+	case MarkerUndefined:
+		tok.Value = Undefined
+
+	case MarkerReference:
+		tok.Value, err = dec.readU16()
+
+	case MarkerDate:
+		var d Date
+		if d.Millis, err = dec.readDouble(); err == nil {
+			d.TZ, err = dec.readS16()
+		}
+		tok.Value = d
+
+	case MarkerObject:
+		tok.Kind = TokenStartObject
+		dec.openFrame(false, 0)
+
+	case MarkerEcmaArray:
+		tok.Kind = TokenStartObject
+		if tok.Length, err = dec.readU32(); err == nil {
+			dec.openFrame(false, 0)
+		}
+
+	case MarkerTypedObject:
+		tok.Kind = TokenStartObject
+		if tok.ClassName, err = dec.readUTF8(); err == nil {
+			dec.openFrame(false, 0)
+		}
+
+	case MarkerStrictArray:
+		tok.Kind = TokenStartArray
+		if tok.Length, err = dec.readU32(); err == nil {
+			if err = dec.limiter.CheckArrayLength(int64(tok.Length)); err == nil {
+				dec.openFrame(true, tok.Length)
+			}
+		}
+
+	case MarkerAVMPlusObject:
+		// Children of the AMF3 value are at the next level of the token
+		if err = dec.enterNest(); err != nil {
+			break
+		}
+		var data []byte
+		data, err = dec.readAMF3Raw()
+		dec.leaveNest()
+		tok.Value = AVMPlusObject(data)
+
+	case MarkerObjectEnd:
+		return Token{}, ErrObjectEndMarker
+
+	default:
+		return Token{}, &UnexpectedMarkerError{
+			Marker: uint8(marker),
+		}
+	}
+
+	if err != nil {
+		return Token{}, err
+	}
+
+	return tok, nil
+}
+
+// Skip Discards the next value without decoding it
+func (dec *Decoder) Skip() error {
+	if err := dec.beginValue(); err != nil {
+		return err
+	}
+
+	return dec.skip()
+}
+
+func (dec *Decoder) skip() error {
+	if err := dec.enterNest(); err != nil {
+		return err
+	}
+	defer dec.leaveNest()
+
+	return dec.skipValue()
+}
+
+// skipValue Discards the next value at the current nesting level. Children of it are skipped at the next level.
+func (dec *Decoder) skipValue() error {
+	marker, err := dec.readU8()
+	if err != nil {
+		return err
+	}
+
+	switch Marker(marker) {
+	case MarkerNumber:
+		err = dec.discard(8)
+
+	case MarkerBoolean:
+		err = dec.discard(1)
+
+	case MarkerString:
+		var l uint16
+		if l, err = dec.readU16(); err == nil {
+			err = dec.discardString(uint32(l))
+		}
+
+	case MarkerLongString, MarkerXMLDocument:
+		var l uint32
+		if l, err = dec.readU32(); err == nil {
+			err = dec.discardString(l)
+		}
+
+	case MarkerNull, MarkerUndefined, MarkerUnsupported:
+		// No payloads
+
+	case MarkerReference:
+		err = dec.discard(2)
+
+	case MarkerDate:
+		err = dec.discard(8 + 2)
+
+	case MarkerObject:
+		dec.refs.Reserve()
+		err = dec.skipProperties()
+
+	case MarkerEcmaArray:
+		if err = dec.discard(4); err == nil {
+			dec.refs.Reserve()
+			err = dec.skipProperties()
+		}
+
+	case MarkerTypedObject:
+		var l uint16
+		if l, err = dec.readU16(); err == nil {
+			if err = dec.discardString(uint32(l)); err == nil {
+				dec.refs.Reserve()
+				err = dec.skipProperties()
+			}
+		}
+
+	case MarkerStrictArray:
+		var length uint32
+		if length, err = dec.readU32(); err == nil {
+			if err = dec.limiter.CheckArrayLength(int64(length)); err != nil {
+				break
+			}
+			dec.refs.Reserve()
+			for i := uint32(0); i < length && err == nil; i++ {
+				err = dec.skip()
+			}
+		}
+
+	case MarkerAVMPlusObject:
+		err = dec.skipAMF3()
+
+	case MarkerObjectEnd:
+		return ErrObjectEndMarker
+
+	default:
+		return &UnexpectedMarkerError{
+			Marker: marker,
+		}
+	}
+
+	return wrapEOF(err)
+}
+
+func (dec *Decoder) skipProperties() error {
+	for numKeys := 1; ; numKeys++ {
+		l, err := dec.readU16()
+		if err != nil {
+			return err
+		}
+
+		if l == 0 {
+			marker, err := dec.readU8()
+			if err != nil {
+				return err
+			}
+			if Marker(marker) != MarkerObjectEnd {
+				return &DecodeError{
+					Message: "Not ended with object-end",
+				}
+			}
+			return nil
+		}
+
+		if err := dec.limiter.CheckObjectKeys(numKeys); err != nil {
+			return err
+		}
+		if err := dec.discardString(uint32(l)); err != nil {
+			return err
+		}
+
+		if err := dec.skip(); err != nil {
+			return wrapEOF(err)
+		}
+	}
+}
+
+// discardString Discards bytes of a string which length is already read
+func (dec *Decoder) discardString(l uint32) error {
+	if err := dec.limiter.CheckStringLength(int64(l)); err != nil {
+		return err
+	}
+
+	return dec.discard(int64(l))
+}
+
+func (dec *Decoder) discard(n int64) error {
+	_, err := io.CopyN(io.Discard, dec, n)
+	return err
+}
+
+// currentFrame Returns the innermost frame if it is opened in the current nesting level of Decode calls
+// Frames opened outside of Unmarshaler implementations are not visible from them.
+func (dec *Decoder) currentFrame() *tokenFrame {
+	if len(dec.frames) == 0 {
+		return nil
+	}
+
+	f := &dec.frames[len(dec.frames)-1]
+	if f.depth != dec.depth {
+		return nil
+	}
+
+	return f
+}
+
+func (dec *Decoder) openFrame(isArray bool, length uint32) {
+	dec.refs.Reserve()
+	dec.frames = append(dec.frames, tokenFrame{
+		isArray:   isArray,
+		remaining: length,
+		depth:     dec.depth,
+	})
+}
+
+// beginValue Consumes a slot of a value in the current frame
+func (dec *Decoder) beginValue() error {
+	f := dec.currentFrame()
+	if f == nil {
+		return nil
+	}
+
+	if err := f.checkValue(); err != nil {
+		return err
+	}
+
+	if f.isArray {
+		f.remaining--
+	} else {
+		f.expectValue = false
+	}
+
+	return nil
+}
+
+func (f *tokenFrame) checkValue() error {
+	if f.isArray && f.remaining == 0 {
+		return &StateError{
+			Message: "No more elements in the array",
+		}
+	}
+
+	if !f.isArray && !f.expectValue {
+		return &StateError{
+			Message: "A key is expected in the object",
+		}
+	}
+
+	return nil
+}
+
+// writerFrame A state of an object or an array which is opened by Begin methods of Encoder
+type writerFrame struct {
+	marker      Marker // MarkerObject, MarkerEcmaArray or MarkerStrictArray
+	remaining   uint32 // elements or properties which must be written, for arrays
+	expectValue bool   // for objects
+	depth       int    // a nesting level of Encode calls when the frame is opened
+}
+
+// WriteNumber Write a Number
+func (enc *Encoder) WriteNumber(n float64) error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	return enc.encodeNumber(reflect.ValueOf(n))
+}
+
+// WriteBoolean Write a Boolean
+func (enc *Encoder) WriteBoolean(b bool) error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	return enc.encodeBoolean(reflect.ValueOf(b))
+}
+
+// WriteString Write a String, or a Long String if the string is longer than 65535 bytes
+func (enc *Encoder) WriteString(s string) error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	return enc.encodeString(reflect.ValueOf(s))
+}
+
+// WriteNull Write a Null
+func (enc *Encoder) WriteNull() error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	return enc.encodeNull()
+}
+
+// WriteUndefined Write an Undefined
+func (enc *Encoder) WriteUndefined() error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	return enc.encodeUndefined()
+}
+
+// BeginObject Write a beginning of an Object. Properties are written by WriteKey and values, and then EndObject must be called.
+func (enc *Encoder) BeginObject() error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	if err := enc.writeU8(uint8(MarkerObject)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	enc.openFrame(MarkerObject, 0)
+
+	return nil
+}
+
+// BeginECMAArray Write a beginning of an ECMA Array. Exactly n properties must be written before EndObject.
+func (enc *Encoder) BeginECMAArray(n uint32) error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	if err := enc.writeU8(uint8(MarkerEcmaArray)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	if err := enc.writeU32(n); err != nil {
+		return err
+	}
+
+	enc.openFrame(MarkerEcmaArray, n)
+
+	return nil
+}
+
+// BeginStrictArray Write a beginning of a Strict Array. Exactly n values must be written before EndArray.
+func (enc *Encoder) BeginStrictArray(n uint32) error {
+	if err := enc.beginValue(); err != nil {
+		return err
+	}
+
+	if err := enc.writeU8(uint8(MarkerStrictArray)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	if err := enc.writeU32(n); err != nil {
+		return err
+	}
+
+	enc.openFrame(MarkerStrictArray, n)
+
+	return nil
+}
+
+// WriteKey Write a key of a property of the Object or the ECMA Array. A value must be written after the key.
+func (enc *Encoder) WriteKey(key string) error {
+	f := enc.currentFrame()
+	if f == nil || f.marker == MarkerStrictArray {
+		return &StateError{
+			Message: "Not in an object",
+		}
+	}
+	if f.expectValue {
+		return &StateError{
+			Message: "A value is expected in the object",
+		}
+	}
+	if key == "" {
+		return fmt.Errorf("key of property must not be empty")
+	}
+
+	if f.marker == MarkerEcmaArray {
+		if f.remaining == 0 {
+			return &StateError{
+				Message: "Too many properties in the ECMA Array",
+			}
+		}
+		f.remaining--
+	}
+
+	if err := enc.writeUTF8(key); err != nil {
+		return err
+	}
+	f.expectValue = true
+
+	return nil
+}
+
+// EndObject Write an end of the Object or the ECMA Array
+func (enc *Encoder) EndObject() error {
+	f := enc.currentFrame()
+	if f == nil || f.marker == MarkerStrictArray {
+		return &StateError{
+			Message: "Not in an object",
+		}
+	}
+	if f.expectValue {
+		return &StateError{
+			Message: "A value is expected in the object",
+		}
+	}
+	if f.remaining != 0 {
+		return &StateError{
+			Message: fmt.Sprintf("Too few properties in the ECMA Array: Remaining = %d", f.remaining),
+		}
+	}
+
+	if err := enc.encodeObjectEnd(); err != nil {
+		return err
+	}
+	enc.frames = enc.frames[:len(enc.frames)-1]
+
+	return nil
+}
+
+// EndArray Finish the Strict Array. Nothing is written because Strict Arrays do not have terminators.
+func (enc *Encoder) EndArray() error {
+	f := enc.currentFrame()
+	if f == nil || f.marker != MarkerStrictArray {
+		return &StateError{
+			Message: "Not in an array",
+		}
+	}
+	if f.remaining != 0 {
+		return &StateError{
+			Message: fmt.Sprintf("Too few elements in the array: Remaining = %d", f.remaining),
+		}
+	}
+
+	enc.frames = enc.frames[:len(enc.frames)-1]
+
+	return nil
+}
+
+// currentFrame Returns the innermost frame if it is opened in the current nesting level of Encode calls
+func (enc *Encoder) currentFrame() *writerFrame {
+	if len(enc.frames) == 0 {
+		return nil
+	}
+
+	f := &enc.frames[len(enc.frames)-1]
+	if f.depth != enc.depth {
+		return nil
+	}
+
+	return f
+}
+
+func (enc *Encoder) openFrame(marker Marker, length uint32) {
+	enc.frames = append(enc.frames, writerFrame{
+		marker:    marker,
+		remaining: length,
+		depth:     enc.depth,
+	})
+}
+
+// beginValue Consumes a slot of a value in the current frame
+func (enc *Encoder) beginValue() error {
+	f := enc.currentFrame()
+	if f == nil {
+		return nil
+	}
+
+	if f.marker == MarkerStrictArray {
+		if f.remaining == 0 {
+			return &StateError{
+				Message: "Too many elements in the array",
+			}
+		}
+		f.remaining--
+		return nil
+	}
+
+	if !f.expectValue {
+		return &StateError{
+			Message: "A key is expected in the object",
+		}
+	}
+	f.expectValue = false
+
+	return nil
+}
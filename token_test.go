@@ -0,0 +1,469 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCommandByTokens(t *testing.T) {
+	bin := []byte{
+		0x02, 0x00, 0x07, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, // String(connect)
+		0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+	}
+	bin = append(bin, orderedObjectTest.Binary...)
+
+	r := bytes.NewReader(bin)
+	dec := NewDecoder(r)
+
+	marker, err := dec.PeekMarker()
+	require.Nil(t, err)
+	require.Equal(t, MarkerString, marker)
+
+	var name string
+	err = dec.Decode(&name)
+	require.Nil(t, err)
+	require.Equal(t, "connect", name)
+
+	var transactionID int
+	err = dec.Decode(&transactionID)
+	require.Nil(t, err)
+	require.Equal(t, 1, transactionID)
+
+	tok, err := dec.Token()
+	require.Nil(t, err)
+	require.Equal(t, Token{Kind: TokenStartObject, Marker: MarkerObject}, tok)
+
+	tok, err = dec.Token()
+	require.Nil(t, err)
+	require.Equal(t, Token{Kind: TokenKey, Key: "app"}, tok)
+
+	var app string
+	err = dec.Decode(&app)
+	require.Nil(t, err)
+	require.Equal(t, "live", app)
+
+	tok, err = dec.Token()
+	require.Nil(t, err)
+	require.Equal(t, Token{Kind: TokenKey, Key: "tcUrl"}, tok)
+
+	err = dec.Skip()
+	require.Nil(t, err)
+
+	tok, err = dec.Token()
+	require.Nil(t, err)
+	require.Equal(t, Token{Kind: TokenKey, Key: "fpad"}, tok)
+
+	tok, err = dec.Token()
+	require.Nil(t, err)
+	require.Equal(t, Token{Kind: TokenValue, Marker: MarkerBoolean, Value: false}, tok)
+
+	tok, err = dec.Token()
+	require.Nil(t, err)
+	require.Equal(t, Token{Kind: TokenEndObject}, tok)
+
+	_, err = dec.Token()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestDecodeTokens(t *testing.T) {
+	bin := []byte{
+		0x0a,                   // Strict Array Marker
+		0x00, 0x00, 0x00, 0x03, // Array length (3: u32) BigEndian
+	}
+	bin = append(bin, orderedECMAArrayTest.Binary...)
+	bin = append(bin, typedObjectTest.Binary...)
+	bin = append(bin, dateWithTimeZoneBinary...)
+
+	r := bytes.NewReader(bin)
+	dec := NewDecoder(r)
+
+	var toks []Token
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		toks = append(toks, tok)
+	}
+
+	require.Equal(t, []Token{
+		{Kind: TokenStartArray, Marker: MarkerStrictArray, Length: 3},
+		{Kind: TokenStartObject, Marker: MarkerEcmaArray, Length: 2},
+		{Kind: TokenKey, Key: "width"},
+		{Kind: TokenValue, Marker: MarkerNumber, Value: float64(2)},
+		{Kind: TokenKey, Key: "height"},
+		{Kind: TokenStartObject, Marker: MarkerObject},
+		{Kind: TokenEndObject},
+		{Kind: TokenEndObject},
+		{Kind: TokenStartObject, Marker: MarkerTypedObject, ClassName: "com.example.User"},
+		{Kind: TokenKey, Key: "name"},
+		{Kind: TokenValue, Marker: MarkerString, Value: "a"},
+		{Kind: TokenKey, Key: "age"},
+		{Kind: TokenValue, Marker: MarkerNumber, Value: float64(20)},
+		{Kind: TokenEndObject},
+		{Kind: TokenValue, Marker: MarkerDate, Value: Date{Millis: 4660000, TZ: 540}},
+		{Kind: TokenEndArray},
+	}, toks)
+}
+
+func TestDecodeSkip(t *testing.T) {
+	allTestCases := append(append([]testCase{}, testCases...), objectTest, referenceTest, typedObjectTest, orderedECMAArrayTest, binaryTest, avmPlusObjectTest)
+
+	for _, tc := range allTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			r := bytes.NewReader(append(append([]byte{}, tc.Binary...), 0x05)) // Null follows
+			dec := NewDecoder(r)
+
+			err := dec.Skip()
+			require.Nil(t, err)
+
+			marker, err := dec.PeekMarker()
+			require.Nil(t, err)
+			require.Equal(t, MarkerNull, marker)
+		})
+	}
+
+	t.Run("unexpected EOF", func(t *testing.T) {
+		r := bytes.NewReader(objectTest.Binary[:len(objectTest.Binary)-1])
+		dec := NewDecoder(r)
+
+		err := dec.Skip()
+		require.Equal(t, io.ErrUnexpectedEOF, err)
+	})
+}
+
+func TestDecodeTokenState(t *testing.T) {
+	t.Run("value instead of key", func(t *testing.T) {
+		r := bytes.NewReader(objectTest.Binary)
+		dec := NewDecoder(r)
+
+		_, err := dec.Token()
+		require.Nil(t, err)
+
+		var v interface{}
+		err = dec.Decode(&v)
+		require.IsType(t, &StateError{}, err)
+
+		_, err = dec.PeekMarker()
+		require.IsType(t, &StateError{}, err)
+
+		err = dec.Skip()
+		require.IsType(t, &StateError{}, err)
+	})
+
+	t.Run("end of array", func(t *testing.T) {
+		bin := []byte{
+			0x0a,                   // Strict Array Marker
+			0x00, 0x00, 0x00, 0x00, // Array length (0: u32) BigEndian
+			0x05, // Null
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		_, err := dec.Token()
+		require.Nil(t, err)
+
+		var v interface{}
+		err = dec.Decode(&v)
+		require.IsType(t, &StateError{}, err)
+
+		tok, err := dec.Token()
+		require.Nil(t, err)
+		require.Equal(t, Token{Kind: TokenEndArray}, tok)
+
+		err = dec.Decode(&v)
+		require.Nil(t, err)
+	})
+
+	t.Run("reference to a value opened by Token", func(t *testing.T) {
+		bin := []byte{
+			0x03,             // Object Marker (reference index 0)
+			0x00, 0x01, 0x61, // Key(a)
+			0x07, 0x00, 0x00, // Reference(0)
+			0x00, 0x00, 0x09, // End
+		}
+
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		_, err := dec.Token()
+		require.Nil(t, err)
+		_, err = dec.Token()
+		require.Nil(t, err)
+
+		var v interface{}
+		err = dec.Decode(&v)
+		require.IsType(t, &DecodeError{}, err)
+	})
+}
+
+// sampleTokenPair Encoded and decoded by tokens as [key, value] arrays
+type sampleTokenPair struct {
+	Key   string
+	Value float64
+}
+
+func (p *sampleTokenPair) UnmarshalAMF0(dec *Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok.Kind != TokenStartArray || tok.Length != 2 {
+		return &DecodeError{Message: "Not a pair"}
+	}
+
+	if err := dec.Decode(&p.Key); err != nil {
+		return err
+	}
+	if err := dec.Decode(&p.Value); err != nil {
+		return err
+	}
+
+	if _, err := dec.Token(); err != nil { // End of the array
+		return err
+	}
+
+	return nil
+}
+
+func (p *sampleTokenPair) MarshalAMF0(enc *Encoder) error {
+	if err := enc.BeginStrictArray(2); err != nil {
+		return err
+	}
+	if err := enc.WriteString(p.Key); err != nil {
+		return err
+	}
+	if err := enc.WriteNumber(p.Value); err != nil {
+		return err
+	}
+	return enc.EndArray()
+}
+
+func TestDecodeTokensInUnmarshaler(t *testing.T) {
+	pair := []byte{
+		0x0a,                   // Strict Array Marker
+		0x00, 0x00, 0x00, 0x02, // Array length (2: u32) BigEndian
+		0x02, 0x00, 0x01, 0x61, // String(a)
+		0x00, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Number(1)
+	}
+	bin := append(append([]byte{0x0a, 0x00, 0x00, 0x00, 0x02}, pair...), pair...) // An array of pairs
+
+	t.Run("after Token", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		tok, err := dec.Token()
+		require.Nil(t, err)
+		require.Equal(t, TokenStartArray, tok.Kind)
+
+		for i := 0; i < 2; i++ {
+			var p sampleTokenPair
+			err = dec.Decode(&p)
+			require.Nil(t, err)
+			require.Equal(t, sampleTokenPair{Key: "a", Value: 1}, p)
+		}
+
+		tok, err = dec.Token()
+		require.Nil(t, err)
+		require.Equal(t, TokenEndArray, tok.Kind)
+	})
+
+	t.Run("in Decode", func(t *testing.T) {
+		r := bytes.NewReader(bin)
+		dec := NewDecoder(r)
+
+		var ps []sampleTokenPair
+		err := dec.Decode(&ps)
+		require.Nil(t, err)
+		require.Equal(t, []sampleTokenPair{{Key: "a", Value: 1}, {Key: "a", Value: 1}}, ps)
+		require.Equal(t, 0, r.Len())
+	})
+}
+
+func TestEncodeTokens(t *testing.T) {
+	t.Run("ECMA Array", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		require.Nil(t, enc.BeginECMAArray(2))
+		require.Nil(t, enc.WriteKey("width"))
+		require.Nil(t, enc.WriteNumber(2))
+		require.Nil(t, enc.WriteKey("height"))
+		require.Nil(t, enc.BeginObject())
+		require.Nil(t, enc.EndObject())
+		require.Nil(t, enc.EndObject())
+
+		require.Equal(t, orderedECMAArrayTest.Binary, buf.Bytes())
+	})
+
+	t.Run("Object with Encode", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		require.Nil(t, enc.BeginObject())
+		require.Nil(t, enc.WriteKey("app"))
+		require.Nil(t, enc.WriteString("live"))
+		require.Nil(t, enc.WriteKey("tcUrl"))
+		require.Nil(t, enc.Encode("rtmp://x"))
+		require.Nil(t, enc.WriteKey("fpad"))
+		require.Nil(t, enc.WriteBoolean(false))
+		require.Nil(t, enc.EndObject())
+
+		require.Equal(t, orderedObjectTest.Binary, buf.Bytes())
+	})
+
+	t.Run("Strict Array", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		require.Nil(t, enc.BeginStrictArray(2))
+		require.Nil(t, enc.WriteNull())
+		require.Nil(t, enc.WriteUndefined())
+		require.Nil(t, enc.EndArray())
+
+		require.Equal(t, []byte{0x0a, 0x00, 0x00, 0x00, 0x02, 0x05, 0x06}, buf.Bytes())
+	})
+
+	t.Run("in Marshaler", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		enc := NewEncoder(buf)
+
+		p := &sampleTokenPair{Key: "a", Value: 1}
+		require.Nil(t, enc.BeginStrictArray(2))
+		require.Nil(t, enc.Encode(p))
+		require.Nil(t, enc.Encode(p))
+		require.Nil(t, enc.EndArray())
+
+		var ps []sampleTokenPair
+		err := NewDecoder(buf).Decode(&ps)
+		require.Nil(t, err)
+		require.Equal(t, []sampleTokenPair{*p, *p}, ps)
+	})
+}
+
+func TestEncodeTokenState(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Write func(enc *Encoder) error
+	}{
+		{
+			Name: "value without key",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginObject()
+				return enc.WriteNumber(1)
+			},
+		},
+		{
+			Name: "Encode without key",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginObject()
+				return enc.Encode(1)
+			},
+		},
+		{
+			Name: "key without value",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginObject()
+				_ = enc.WriteKey("a")
+				return enc.WriteKey("b")
+			},
+		},
+		{
+			Name: "end without value",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginObject()
+				_ = enc.WriteKey("a")
+				return enc.EndObject()
+			},
+		},
+		{
+			Name: "key in array",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginStrictArray(1)
+				return enc.WriteKey("a")
+			},
+		},
+		{
+			Name: "mismatched end of object",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginStrictArray(0)
+				return enc.EndObject()
+			},
+		},
+		{
+			Name: "mismatched end of array",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginObject()
+				return enc.EndArray()
+			},
+		},
+		{
+			Name: "end without begin",
+			Write: func(enc *Encoder) error {
+				return enc.EndObject()
+			},
+		},
+		{
+			Name: "too many elements",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginStrictArray(1)
+				_ = enc.WriteNull()
+				return enc.WriteNull()
+			},
+		},
+		{
+			Name: "too few elements",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginStrictArray(2)
+				_ = enc.WriteNull()
+				return enc.EndArray()
+			},
+		},
+		{
+			Name: "too many properties",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginECMAArray(0)
+				return enc.WriteKey("a")
+			},
+		},
+		{
+			Name: "too few properties",
+			Write: func(enc *Encoder) error {
+				_ = enc.BeginECMAArray(1)
+				return enc.EndObject()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			enc := NewEncoder(bytes.NewBuffer([]byte{}))
+
+			err := tc.Write(enc)
+			require.IsType(t, &StateError{}, err)
+		})
+	}
+
+	t.Run("empty key", func(t *testing.T) {
+		enc := NewEncoder(bytes.NewBuffer([]byte{}))
+
+		require.Nil(t, enc.BeginObject())
+		require.Error(t, enc.WriteKey(""))
+	})
+}
@@ -0,0 +1,420 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Value A generic tree of AMF0 values which keeps encoded representations as is
+// Decoding into Value and encoding it again produces the same bytes. References are not resolved and kept as Reference values.
+type Value struct {
+	// Kind A marker of the value. Fields which are not related to the kind are ignored
+	Kind Marker
+
+	// Number A value of MarkerNumber
+	Number float64
+	// Boolean A value of MarkerBoolean
+	Boolean bool
+	// BooleanByte An encoded byte of MarkerBoolean. It is written as is if it agrees with Boolean, thus bytes other than 0x00 and 0x01 are kept
+	BooleanByte uint8
+	// String A value of MarkerString, MarkerLongString and MarkerXMLDocument
+	String string
+	// ClassName A class name of MarkerTypedObject
+	ClassName string
+	// Properties Properties of MarkerObject, MarkerEcmaArray and MarkerTypedObject in the encoded order
+	Properties []Property
+	// Length An associative count of MarkerEcmaArray. It is written as is, thus it may differ from the number of properties
+	Length uint32
+	// Elements Elements of MarkerStrictArray
+	Elements []*Value
+	// Date A value of MarkerDate
+	Date Date
+	// Reference An index of MarkerReference
+	Reference uint16
+	// AMF3 An AMF3 value of MarkerAVMPlusObject. It is kept as is
+	AMF3 AVMPlusObject
+}
+
+// Property A key-value pair of objects in Value
+type Property struct {
+	Key   string
+	Value *Value
+}
+
+// Get Returns a value at the path. The path is keys of properties or indexes of elements separated by dots, such as "a.b.0".
+func (v *Value) Get(path string) (*Value, bool) {
+	cur := v
+	for _, key := range strings.Split(path, ".") {
+		next, ok := cur.child(key)
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+
+	return cur, true
+}
+
+func (v *Value) child(key string) (*Value, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	switch v.Kind {
+	case MarkerObject, MarkerEcmaArray, MarkerTypedObject:
+		for _, p := range v.Properties {
+			if p.Key == key {
+				return p.Value, p.Value != nil
+			}
+		}
+
+	case MarkerStrictArray:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(v.Elements) {
+			return nil, false
+		}
+		return v.Elements[i], v.Elements[i] != nil
+	}
+
+	return nil, false
+}
+
+// Set Replaces a value of the property which has the key, or appends the property if not exists.
+// Length of MarkerEcmaArray is incremented when the property is appended.
+func (v *Value) Set(key string, value *Value) error {
+	switch v.Kind {
+	case MarkerObject, MarkerEcmaArray, MarkerTypedObject:
+		// Do nothing
+	default:
+		return fmt.Errorf("value does not have properties: Kind = %+v", v.Kind)
+	}
+
+	for i := range v.Properties {
+		if v.Properties[i].Key == key {
+			v.Properties[i].Value = value
+			return nil
+		}
+	}
+
+	v.Properties = append(v.Properties, Property{
+		Key:   key,
+		Value: value,
+	})
+	if v.Kind == MarkerEcmaArray && v.Length < math.MaxUint32 {
+		v.Length++
+	}
+
+	return nil
+}
+
+// AsNumber Returns a value of MarkerNumber
+func (v *Value) AsNumber() (float64, bool) {
+	if v == nil || v.Kind != MarkerNumber {
+		return 0, false
+	}
+	return v.Number, true
+}
+
+// AsBool Returns a value of MarkerBoolean
+func (v *Value) AsBool() (bool, bool) {
+	if v == nil || v.Kind != MarkerBoolean {
+		return false, false
+	}
+	return v.Boolean, true
+}
+
+// AsString Returns a value of MarkerString, MarkerLongString or MarkerXMLDocument
+func (v *Value) AsString() (string, bool) {
+	if v == nil {
+		return "", false
+	}
+
+	switch v.Kind {
+	case MarkerString, MarkerLongString, MarkerXMLDocument:
+		return v.String, true
+	default:
+		return "", false
+	}
+}
+
+// MarshalAMF0 Implements Marshaler
+func (v *Value) MarshalAMF0(enc *Encoder) error {
+	return enc.encodeTree(v)
+}
+
+// UnmarshalAMF0 Implements Unmarshaler
+func (v *Value) UnmarshalAMF0(dec *Decoder) error {
+	return dec.decodeTree(v)
+}
+
+func (enc *Encoder) encodeTree(v *Value) error {
+	if v == nil {
+		return enc.encodeNull()
+	}
+
+	switch v.Kind {
+	case MarkerNumber, MarkerBoolean, MarkerNull, MarkerUndefined, MarkerDate, MarkerReference, MarkerUnsupported:
+		// Primitive values
+
+	case MarkerString:
+		if err := enc.writeU8(uint8(v.Kind)); err != nil {
+			return err
+		}
+		return enc.writeUTF8(v.String)
+
+	case MarkerLongString, MarkerXMLDocument:
+		if uint64(len(v.String)) > math.MaxUint32 {
+			return fmt.Errorf("too long string: Expected <= %d, Actual = %d", uint32(math.MaxUint32), len(v.String))
+		}
+		if err := enc.writeU8(uint8(v.Kind)); err != nil {
+			return err
+		}
+		return enc.writeUTF8Long(v.String)
+
+	case MarkerObject, MarkerEcmaArray, MarkerTypedObject, MarkerStrictArray:
+		return enc.encodeTreeComplex(v)
+
+	case MarkerAVMPlusObject:
+		if v.AMF3 == nil {
+			return fmt.Errorf("AMF3 of Value must not be nil")
+		}
+		return v.AMF3.MarshalAMF0(enc)
+
+	default:
+		return fmt.Errorf("unexpected kind of Value: Kind = %+v", v.Kind)
+	}
+
+	if err := enc.writeU8(uint8(v.Kind)); err != nil {
+		return err
+	}
+
+	switch v.Kind {
+	case MarkerNumber:
+		return enc.writeDouble(v.Number)
+
+	case MarkerBoolean:
+		b := v.BooleanByte
+		if (b != 0) != v.Boolean {
+			b = 0
+			if v.Boolean {
+				b = 1
+			}
+		}
+		return enc.writeU8(b)
+
+	case MarkerDate:
+		if err := enc.writeDouble(v.Date.Millis); err != nil {
+			return err
+		}
+		return enc.writeS16(v.Date.TZ)
+
+	case MarkerReference:
+		return enc.writeU16(v.Reference)
+	}
+
+	return nil // Null, Undefined and Unsupported have no payloads
+}
+
+func (enc *Encoder) encodeTreeComplex(v *Value) error {
+	if err := enc.writeU8(uint8(v.Kind)); err != nil {
+		return err
+	}
+	enc.numObjects++
+
+	switch v.Kind {
+	case MarkerTypedObject:
+		if err := enc.writeUTF8(v.ClassName); err != nil {
+			return err
+		}
+
+	case MarkerEcmaArray:
+		if err := enc.writeU32(v.Length); err != nil {
+			return err
+		}
+
+	case MarkerStrictArray:
+		if uint64(len(v.Elements)) > math.MaxUint32 {
+			return fmt.Errorf("too many elements: Expected <= %d, Actual = %d", uint32(math.MaxUint32), len(v.Elements))
+		}
+		if err := enc.writeU32(uint32(len(v.Elements))); err != nil {
+			return err
+		}
+
+		for _, elem := range v.Elements {
+			if err := enc.encodeTree(elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, p := range v.Properties {
+		if p.Key == "" {
+			return fmt.Errorf("key of property must not be empty")
+		}
+		if err := enc.writeUTF8(p.Key); err != nil {
+			return err
+		}
+
+		if err := enc.encodeTree(p.Value); err != nil {
+			return err
+		}
+	}
+
+	return enc.encodeObjectEnd()
+}
+
+// decodeNestedTree Decodes a property or an element of the tree at the next nesting level
+func (dec *Decoder) decodeNestedTree(v *Value) error {
+	if err := dec.enterNest(); err != nil {
+		return err
+	}
+	defer dec.leaveNest()
+
+	return dec.decodeTree(v)
+}
+
+func (dec *Decoder) decodeTree(v *Value) error {
+	marker, err := dec.readU8()
+	if err != nil {
+		return err
+	}
+
+	*v = Value{
+		Kind: Marker(marker),
+	}
+
+	switch v.Kind {
+	case MarkerNumber:
+		v.Number, err = dec.readDouble()
+
+	case MarkerBoolean:
+		v.BooleanByte, err = dec.readU8()
+		v.Boolean = v.BooleanByte != 0
+
+	case MarkerString:
+		v.String, err = dec.readUTF8()
+
+	case MarkerLongString, MarkerXMLDocument:
+		v.String, err = dec.readUTF8Long()
+
+	case MarkerNull, MarkerUndefined, MarkerUnsupported:
+		// No payloads
+
+	case MarkerReference:
+		v.Reference, err = dec.readU16()
+
+	case MarkerDate:
+		if v.Date.Millis, err = dec.readDouble(); err == nil {
+			v.Date.TZ, err = dec.readS16()
+		}
+
+	case MarkerObject:
+		dec.refs.Add(reflect.ValueOf(v).Elem())
+		v.Properties, err = dec.decodeTreeProperties()
+
+	case MarkerEcmaArray:
+		if v.Length, err = dec.readU32(); err == nil {
+			dec.refs.Add(reflect.ValueOf(v).Elem())
+			v.Properties, err = dec.decodeTreeProperties()
+		}
+
+	case MarkerTypedObject:
+		if v.ClassName, err = dec.readUTF8(); err == nil {
+			dec.refs.Add(reflect.ValueOf(v).Elem())
+			v.Properties, err = dec.decodeTreeProperties()
+		}
+
+	case MarkerStrictArray:
+		var length uint32
+		if length, err = dec.readU32(); err == nil {
+			if err = dec.limiter.CheckArrayLength(int64(length)); err != nil {
+				break
+			}
+			dec.refs.Add(reflect.ValueOf(v).Elem())
+			v.Elements, err = dec.decodeTreeElements(length)
+		}
+
+	case MarkerAVMPlusObject:
+		var data []byte
+		data, err = dec.readAMF3Raw()
+		v.AMF3 = AVMPlusObject(data)
+
+	case MarkerObjectEnd:
+		return ErrObjectEndMarker
+
+	default:
+		return &UnexpectedMarkerError{
+			Marker: marker,
+		}
+	}
+
+	return wrapEOF(err)
+}
+
+func (dec *Decoder) decodeTreeProperties() ([]Property, error) {
+	props := []Property{}
+	for numKeys := 1; ; numKeys++ {
+		key, err := dec.readUTF8()
+		if err != nil {
+			return nil, wrapEOF(err)
+		}
+
+		if key == "" {
+			marker, err := dec.readU8()
+			if err != nil {
+				return nil, wrapEOF(err)
+			}
+			if Marker(marker) != MarkerObjectEnd {
+				return nil, &DecodeError{
+					Message: "Not ended with object-end",
+				}
+			}
+			break
+		}
+
+		if err := dec.limiter.CheckObjectKeys(numKeys); err != nil {
+			return nil, err
+		}
+
+		value := &Value{}
+		if err := dec.decodeNestedTree(value); err != nil {
+			return nil, wrapEOF(err)
+		}
+
+		props = append(props, Property{
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	return props, nil
+}
+
+func (dec *Decoder) decodeTreeElements(length uint32) ([]*Value, error) {
+	if length > math.MaxInt32 {
+		return nil, fmt.Errorf("unsupported array length: Expected <= %d, Actual = %d", math.MaxInt32, length)
+	}
+
+	// Elements are appended one by one not to allocate a large slice by a broken length
+	elems := []*Value{}
+	for i := 0; i < int(length); i++ {
+		value := &Value{}
+		if err := dec.decodeNestedTree(value); err != nil {
+			return nil, wrapEOF(err)
+		}
+		elems = append(elems, value)
+	}
+
+	return elems, nil
+}
@@ -0,0 +1,232 @@
+//
+// Copyright (c) 2018- yutopp (yutopp@gmail.com)
+//
+// Distributed under the Boost Software License, Version 1.0. (See accompanying
+// file LICENSE_1_0.txt or copy at  https://www.boost.org/LICENSE_1_0.txt)
+//
+
+package amf0
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	allTestCases := append(append([]testCase{}, testCases...), objectTest, referenceTest, typedObjectTest, unregisteredTypedObjectTest, marshalerObjectTest, binaryTest)
+	allTestCases = append(allTestCases, testCase{
+		Name:   "Date with time zone",
+		Binary: dateWithTimeZoneBinary,
+	}, testCase{
+		Name:   "Boolean which is not 0x00 or 0x01",
+		Binary: []byte{0x01, 0x02}, // Boolean Marker, Value(2: u8)
+	}, testCase{
+		Name: "ECMA Array which has an inconsistent count",
+		Binary: []byte{
+			0x08,                   // ECMA Array Marker
+			0x00, 0x00, 0x00, 0x00, // Associative count(0: u32) BigEndian
+			0x00, 0x01, 0x61, // Key(a)
+			0x06,             // Undefined
+			0x00, 0x00, 0x09, // End
+		},
+	})
+
+	for _, tc := range allTestCases {
+		tc := tc // capture
+
+		t.Run(tc.Name, func(t *testing.T) {
+			r := bytes.NewReader(tc.Binary)
+			dec := NewDecoder(r)
+
+			var v Value
+			err := dec.Decode(&v)
+			require.Nil(t, err)
+			require.Equal(t, 0, r.Len())
+
+			buf := bytes.NewBuffer([]byte{})
+			enc := NewEncoder(buf)
+
+			err = enc.Encode(&v)
+			require.Nil(t, err)
+			require.Equal(t, tc.Binary, buf.Bytes())
+		})
+	}
+}
+
+func TestValueGet(t *testing.T) {
+	r := bytes.NewReader(referenceTest.Binary)
+	dec := NewDecoder(r)
+
+	var v Value
+	err := dec.Decode(&v)
+	require.Nil(t, err)
+	require.Equal(t, MarkerObject, v.Kind)
+
+	a, ok := v.Get("x.a")
+	require.True(t, ok)
+	s, ok := a.AsString()
+	require.True(t, ok)
+	require.Equal(t, "s", s)
+
+	b, ok := v.Get("x.b")
+	require.True(t, ok)
+	n, ok := b.AsNumber()
+	require.True(t, ok)
+	require.Equal(t, float64(42), n)
+
+	_, ok = b.AsString()
+	require.False(t, ok)
+
+	y, ok := v.Get("y")
+	require.True(t, ok)
+	require.Equal(t, MarkerReference, y.Kind)
+	require.Equal(t, uint16(1), y.Reference)
+
+	_, ok = v.Get("x.c")
+	require.False(t, ok)
+	_, ok = v.Get("x.a.b")
+	require.False(t, ok)
+}
+
+func TestValueGetElements(t *testing.T) {
+	v := &Value{
+		Kind: MarkerStrictArray,
+		Elements: []*Value{
+			{Kind: MarkerBoolean, Boolean: true},
+		},
+	}
+
+	e, ok := v.Get("0")
+	require.True(t, ok)
+	tf, ok := e.AsBool()
+	require.True(t, ok)
+	require.True(t, tf)
+
+	_, ok = v.Get("1")
+	require.False(t, ok)
+	_, ok = v.Get("-1")
+	require.False(t, ok)
+}
+
+func TestValueModify(t *testing.T) {
+	r := bytes.NewReader(objectTest.Binary)
+	dec := NewDecoder(r)
+
+	var v Value
+	err := dec.Decode(&v)
+	require.Nil(t, err)
+
+	b, ok := v.Get("b")
+	require.True(t, ok)
+	b.Number = 43
+
+	buf := bytes.NewBuffer([]byte{})
+	enc := NewEncoder(buf)
+
+	err = enc.Encode(&v)
+	require.Nil(t, err)
+
+	expected := append([]byte{}, objectTest.Binary...)
+	i := bytes.Index(expected, []byte{0x40, 0x45})
+	require.NotEqual(t, -1, i)
+	expected[i+2] = 0x80 // 42 -> 43
+	require.Equal(t, expected, buf.Bytes())
+
+	var o sampleObject
+	err = NewDecoder(buf).Decode(&o)
+	require.Nil(t, err)
+	require.Equal(t, sampleObject{A: "s", B: 43}, o)
+}
+
+func TestValueModifyBoolean(t *testing.T) {
+	var v Value
+	err := NewDecoder(bytes.NewReader([]byte{0x01, 0x02})).Decode(&v)
+	require.Nil(t, err)
+
+	tf, ok := v.AsBool()
+	require.True(t, ok)
+	require.True(t, tf)
+
+	v.Boolean = false
+
+	buf := bytes.NewBuffer([]byte{})
+	err = NewEncoder(buf).Encode(&v)
+	require.Nil(t, err)
+	require.Equal(t, []byte{0x01, 0x00}, buf.Bytes())
+}
+
+func TestValueSet(t *testing.T) {
+	t.Run("ECMA Array", func(t *testing.T) {
+		v := &Value{
+			Kind: MarkerEcmaArray,
+		}
+
+		err := v.Set("a", &Value{Kind: MarkerNumber, Number: 1})
+		require.Nil(t, err)
+		err = v.Set("b", &Value{Kind: MarkerNull})
+		require.Nil(t, err)
+		err = v.Set("a", &Value{Kind: MarkerNumber, Number: 2})
+		require.Nil(t, err)
+
+		require.Equal(t, uint32(2), v.Length)
+		require.Equal(t, []Property{
+			{Key: "a", Value: &Value{Kind: MarkerNumber, Number: 2}},
+			{Key: "b", Value: &Value{Kind: MarkerNull}},
+		}, v.Properties)
+	})
+
+	t.Run("not object", func(t *testing.T) {
+		v := &Value{
+			Kind: MarkerNumber,
+		}
+
+		err := v.Set("a", &Value{Kind: MarkerNull})
+		require.Error(t, err)
+	})
+}
+
+func TestValueInStruct(t *testing.T) {
+	type message struct {
+		Name string `amf0:"name"`
+		Args *Value `amf0:"args"`
+	}
+
+	bin := []byte{
+		0x03,                               // Object Marker
+		0x00, 0x04, 0x6e, 0x61, 0x6d, 0x65, // Key(name)
+		0x02, 0x00, 0x01, 0x61, // String(a)
+		0x00, 0x04, 0x61, 0x72, 0x67, 0x73, // Key(args)
+		0x06,             // Undefined
+		0x00, 0x00, 0x09, // End
+	}
+
+	var m message
+	err := NewDecoder(bytes.NewReader(bin)).Decode(&m)
+	require.Nil(t, err)
+	require.Equal(t, "a", m.Name)
+	require.Nil(t, m.Args) // Undefined into a pointer is nil as other types
+
+	m.Args = &Value{Kind: MarkerUndefined}
+
+	buf := bytes.NewBuffer([]byte{})
+	err = NewEncoder(buf).Encode(&m)
+	require.Nil(t, err)
+	require.Equal(t, bin, buf.Bytes())
+}
+
+func TestValueEncodeErrors(t *testing.T) {
+	enc := NewEncoder(bytes.NewBuffer([]byte{}))
+
+	err := enc.Encode(&Value{Kind: MarkerObjectEnd})
+	require.Error(t, err)
+
+	err = enc.Encode(&Value{
+		Kind: MarkerObject,
+		Properties: []Property{
+			{Key: "", Value: &Value{Kind: MarkerNull}},
+		},
+	})
+	require.Error(t, err)
+}